@@ -0,0 +1,311 @@
+package structlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Overflow is a policy used by AsyncPrinter when its queue is full.
+type Overflow byte
+
+// Overflow policies.
+const (
+	OverflowBlock      Overflow = iota // Wait until queue has free space.
+	OverflowDropNewest                 // Drop new record.
+	OverflowDropOldest                 // Drop oldest queued record.
+	OverflowDropBelow                  // Drop new record if its level is below overflow level, otherwise wait.
+)
+
+// Defaults.
+const (
+	DefaultAsyncOverflowLevel = WRN
+)
+
+// Timeout used by Fatal, Fatalf and Fatalln to flush output.
+const fatalFlushTimeout = 5 * time.Second
+
+// Flusher is implemented by Printers which buffer output.
+type Flusher interface {
+	// Flush waits until all buffered output will be written or ctx is
+	// done.
+	Flush(ctx context.Context) error
+}
+
+// levelPrinter is a Printer which needs log level of formatted log
+// record.
+type levelPrinter interface {
+	Printer
+	// printLevel must not keep line after return.
+	printLevel(level Level, line []byte)
+}
+
+// AsyncPrinter is a Printer which queues formatted log records into a
+// bounded buffer and outputs them using another Printer in background.
+//
+// When some records were dropped because of overflow AsyncPrinter will
+// output a WRN log record with KeyMessage "log records dropped" and
+// number of dropped records in "dropped" key, using logger set by
+// SetReportLogger.
+//
+// Use Flush or Close to make sure all records were output, e.g. before
+// exit. Fatal, Fatalf and Fatalln will call Flush automatically.
+type AsyncPrinter struct {
+	mu        sync.Mutex
+	notEmpty  sync.Cond
+	notFull   sync.Cond
+	p         Printer
+	overflow  Overflow
+	level     Level
+	reportLog *Logger
+	queue     []asyncEntry
+	head      int
+	n         int
+	seq       uint64 // Sequence number of last queued record.
+	written   uint64 // Sequence number of last output record.
+	dropped   int
+	closed    bool
+	waiters   []asyncWaiter
+	done      chan struct{}
+}
+
+type asyncEntry struct {
+	seq   uint64
+	level Level
+	line  []byte
+}
+
+// asyncWaiter is used by Flush to wait until record with sequence number
+// seq will be output.
+type asyncWaiter struct {
+	seq uint64
+	ch  chan struct{}
+}
+
+// NewAsyncPrinter returns AsyncPrinter which outputs using p and can
+// queue up to size records. It uses OverflowBlock policy by default.
+func NewAsyncPrinter(p Printer, size int) *AsyncPrinter {
+	if size <= 0 {
+		panic("NewAsyncPrinter called with non-positive size")
+	}
+	a := &AsyncPrinter{
+		p:     p,
+		level: DefaultAsyncOverflowLevel,
+		queue: make([]asyncEntry, size),
+		done:  make(chan struct{}),
+	}
+	a.notEmpty.L = &a.mu
+	a.notFull.L = &a.mu
+	go a.run()
+	return a
+}
+
+// NewAsyncWriter returns AsyncPrinter which outputs to w in the same way
+// as SetOutput does.
+func NewAsyncWriter(w io.Writer, size int) *AsyncPrinter {
+	return NewAsyncPrinter(newWriterPrinter(w), size)
+}
+
+// SetOverflow changes policy used when queue is full (default value is
+// OverflowBlock).
+//
+// It returns a just for convenience.
+func (a *AsyncPrinter) SetOverflow(policy Overflow) *AsyncPrinter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.overflow = policy
+	return a
+}
+
+// SetOverflowLevel changes level used by OverflowDropBelow policy
+// (default value is DefaultAsyncOverflowLevel).
+//
+// It returns a just for convenience.
+func (a *AsyncPrinter) SetOverflowLevel(level Level) *AsyncPrinter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.level = level
+	return a
+}
+
+// SetReportLogger changes logger used to report dropped records (default
+// value is New(KeyUnit, "structlog") with same Printer as a uses).
+// It must not output using a.
+//
+// It returns a just for convenience.
+func (a *AsyncPrinter) SetReportLogger(l *Logger) *AsyncPrinter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reportLog = l
+	return a
+}
+
+// Print implements Printer. It queues v formatted in the manner of
+// [fmt.Print] with INF level.
+func (a *AsyncPrinter) Print(v ...any) {
+	a.printLevel(INF, []byte(strings.TrimSuffix(fmt.Sprint(v...), "\n")))
+}
+
+func (a *AsyncPrinter) printLevel(level Level, line []byte) {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		printLine(a.p, level, line)
+		return
+	}
+	defer a.mu.Unlock()
+	for a.n == len(a.queue) {
+		switch {
+		case a.overflow == OverflowDropNewest,
+			a.overflow == OverflowDropBelow && level < a.level:
+			a.dropped++
+			return
+		case a.overflow == OverflowDropOldest:
+			a.head = (a.head + 1) % len(a.queue)
+			a.n--
+			a.dropped++
+		default:
+			a.notFull.Wait()
+			if a.closed {
+				a.mu.Unlock()
+				printLine(a.p, level, line)
+				a.mu.Lock()
+				return
+			}
+		}
+	}
+	a.seq++
+	e := &a.queue[(a.head+a.n)%len(a.queue)]
+	e.seq = a.seq
+	e.level = level
+	e.line = append(e.line[:0], line...)
+	a.n++
+	a.notEmpty.Signal()
+}
+
+// Flush implements Flusher. It waits until all records queued before
+// Flush call will be output (records queued after Flush call won't delay
+// it).
+func (a *AsyncPrinter) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	if a.written >= a.seq {
+		a.mu.Unlock()
+		return nil
+	}
+	w := asyncWaiter{seq: a.seq, ch: make(chan struct{})}
+	a.waiters = append(a.waiters, w)
+	a.mu.Unlock()
+	select {
+	case <-w.ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close outputs all queued records and stops background goroutine.
+// Records printed after Close will be output synchronously.
+func (a *AsyncPrinter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.notEmpty.Signal()
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+	<-a.done
+	return nil
+}
+
+// run outputs queued records until a is closed.
+func (a *AsyncPrinter) run() {
+	defer close(a.done)
+	var e asyncEntry
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for {
+		for a.n == 0 && !a.closed {
+			a.notEmpty.Wait()
+		}
+		if a.n == 0 {
+			a.notifyWaiters()
+			return
+		}
+		e, a.queue[a.head] = a.queue[a.head], asyncEntry{line: e.line[:0]}
+		a.head = (a.head + 1) % len(a.queue)
+		a.n--
+		dropped := a.dropped
+		a.dropped = 0
+		reportLog := a.reportLog
+		a.notFull.Signal()
+		a.mu.Unlock()
+
+		printLine(a.p, e.level, e.line)
+		if dropped > 0 {
+			if reportLog == nil {
+				reportLog = New(KeyUnit, "structlog").SetPrinter(a.p)
+			}
+			reportLog.Warn("log records dropped", "dropped", dropped)
+		}
+
+		a.mu.Lock()
+		a.written = e.seq
+		a.notifyWaiters()
+	}
+}
+
+// notifyWaiters notifies waiters of already output records. Records
+// dropped by OverflowDropOldest are considered output when next record is
+// output.
+//
+// It must be called with a.mu locked.
+func (a *AsyncPrinter) notifyWaiters() {
+	if len(a.waiters) == 0 {
+		return
+	}
+	a.waiters = slices.DeleteFunc(a.waiters, func(w asyncWaiter) bool {
+		if w.seq <= a.written {
+			close(w.ch)
+			return true
+		}
+		return false
+	})
+}
+
+// Flush waits until all buffered output of l's Printer and outputs (if
+// they implements Flusher, like AsyncPrinter) will be written or ctx is
+// done.
+func (l *Logger) Flush(ctx context.Context) error {
+	l.enabled(DBG) // Call mergeParent.
+	l.mu.RLock()
+	printers := []Printer{l.printer}
+	for _, out := range l.outputs {
+		out.enabled(DBG) // Call mergeParent.
+		out.mu.RLock()
+		printers = append(printers, out.printer)
+		out.mu.RUnlock()
+	}
+	l.mu.RUnlock()
+
+	var errs []error
+	for _, p := range printers {
+		if f, ok := p.(Flusher); ok {
+			errs = append(errs, f.Flush(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flushBeforeExit is used by Fatal, Fatalf and Fatalln.
+func (l *Logger) flushBeforeExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), fatalFlushTimeout)
+	defer cancel()
+	_ = l.Flush(ctx)
+}
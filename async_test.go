@@ -0,0 +1,179 @@
+package structlog_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+// gateWriter blocks first Write until gate is closed.
+type gateWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	gate    chan struct{}
+	once    sync.Once
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{started: make(chan struct{}), gate: make(chan struct{})}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.started)
+		<-w.gate
+	})
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncPrinter(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	a := structlog.NewAsyncWriter(&buf, 2)
+	log := structlog.NewZeroLogger().SetPrinter(a).SetKeyValFormat("%[2]v")
+	for _, msg := range []string{"1", "2", "3", "4", "5"} {
+		log.Info(msg)
+	}
+	a.Print("6\n")
+	t.Nil(log.Flush(context.Background()))
+	t.Equal(buf.String(), "1\n2\n3\n4\n5\n6\n")
+
+	t.Nil(a.Close())
+	t.Nil(a.Close())
+	log.Info("7")
+	t.Equal(buf.String(), "1\n2\n3\n4\n5\n6\n7\n")
+}
+
+func TestAsyncPrinterOverflow(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	tests := []struct {
+		policy structlog.Overflow
+		want   string
+	}{
+		{structlog.OverflowBlock, "1\n2\n3\n4\n5\n"},
+		{structlog.OverflowDropNewest, "1\n2\nlog records dropped dropped=2\n3\n"},
+		{structlog.OverflowDropOldest, "1\n4\nlog records dropped dropped=2\n5\n"},
+		{structlog.OverflowDropBelow, "1\n2\nlog records dropped dropped=1\n3\n5\n"},
+	}
+	for _, tc := range tests {
+		t.Run("", func(tt *testing.T) {
+			t := check.T(tt)
+			t.Parallel()
+			w := newGateWriter()
+			report := structlog.NewZeroLogger().SetOutput(w).SetKeysFormat(map[string]string{structlog.KeyMessage: "%[2]v"})
+			a := structlog.NewAsyncWriter(w, 2).SetOverflow(tc.policy).SetReportLogger(report)
+			defer a.Close()
+			log := structlog.NewZeroLogger().SetPrinter(a).SetKeyValFormat("%[2]v")
+
+			log.Info("1")
+			<-w.started
+			log.Info("2")
+			log.Debug("3")
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				log.Debug("4")
+				log.Warn("5")
+			}()
+			switch tc.policy {
+			case structlog.OverflowBlock, structlog.OverflowDropBelow:
+				time.Sleep(10 * time.Millisecond) // Wait until blocked.
+				t.Equal(w.String(), "")
+			default:
+				<-done
+			}
+			close(w.gate)
+			<-done
+			t.Nil(log.Flush(context.Background()))
+			t.Equal(w.String(), tc.want)
+		})
+	}
+}
+
+func TestAsyncPrinterFlushTimeout(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	w := newGateWriter()
+	a := structlog.NewAsyncWriter(w, 1)
+	defer a.Close()
+	defer close(w.gate)
+	a.Print("1")
+	<-w.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	t.Err(a.Flush(ctx), context.DeadlineExceeded)
+}
+
+type slowWriter struct{ w io.Writer }
+
+func (w slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(50 * time.Millisecond)
+	return w.w.Write(p)
+}
+
+func TestAsyncPrinterFlushUnderLoad(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	w := newGateWriter()
+	close(w.gate)
+	a := structlog.NewAsyncWriter(slowWriter{w}, 2)
+	defer a.Close()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				a.Print("load")
+			}
+		}
+	}()
+	<-w.started
+
+	a.Print("mark")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	t.Nil(a.Flush(ctx), "queue is never empty")
+	t.Contains(w.String(), "mark\n")
+}
+
+func TestAsyncPrinterFatal(tt *testing.T) {
+	if os.Getenv("TEST_ASYNC_FATAL") != "" {
+		log := structlog.NewZeroLogger().SetPrinter(structlog.NewAsyncWriter(slowWriter{os.Stdout}, 8))
+		log.Info("first")
+		log.Fatal("last")
+	}
+	t := check.T(tt)
+	t.Parallel()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestAsyncPrinterFatal$") //nolint:gosec // False positive.
+	cmd.Env = append(os.Environ(), "TEST_ASYNC_FATAL=1")
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	t.True(errors.As(err, &exitErr))
+	t.Equal(exitErr.ExitCode(), 1)
+	t.Equal(string(out), " _m=first\n _m=last\n")
+}
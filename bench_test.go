@@ -0,0 +1,74 @@
+package structlog_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/powerman/structlog"
+)
+
+func BenchmarkDisabled(b *testing.B) {
+	log := structlog.New().SetOutput(io.Discard).SetLogLevel(structlog.INF)
+	b.ReportAllocs()
+	for b.Loop() {
+		log.Debug("msg", "k", 42)
+	}
+}
+
+func BenchmarkText(b *testing.B) {
+	log := structlog.New().SetOutput(io.Discard)
+	b.ReportAllocs()
+	for b.Loop() {
+		log.Info("msg", "k", 42)
+	}
+}
+
+func BenchmarkTextParallel(b *testing.B) {
+	log := structlog.New().SetOutput(io.Discard)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			log.Info("msg", "k", 42)
+		}
+	})
+}
+
+func BenchmarkTextKeyvals(b *testing.B) {
+	log := structlog.New().SetOutput(io.Discard)
+	b.ReportAllocs()
+	for b.Loop() {
+		log.Info("msg", "a", "text", "b", true, "c", 42, "d", 3.14, "e", io.EOF)
+	}
+}
+
+func BenchmarkJSON(b *testing.B) {
+	log := structlog.New().SetOutput(io.Discard).SetLogFormat(structlog.JSON)
+	b.ReportAllocs()
+	for b.Loop() {
+		log.Info("msg", "k", 42)
+	}
+}
+
+func BenchmarkTypedJSON(b *testing.B) {
+	log := structlog.New().SetOutput(io.Discard).SetLogFormat(structlog.JSON).SetTypedJSON(true)
+	b.ReportAllocs()
+	for b.Loop() {
+		log.Info("msg", "k", 42)
+	}
+}
+
+func BenchmarkLogfmt(b *testing.B) {
+	log := structlog.New().SetOutput(io.Discard).SetLogFormat(structlog.Logfmt)
+	b.ReportAllocs()
+	for b.Loop() {
+		log.Info("msg", "k", 42)
+	}
+}
+
+func BenchmarkPrinter(b *testing.B) {
+	log := structlog.New().SetPrinter(structlog.PrinterFunc(func(...any) {}))
+	b.ReportAllocs()
+	for b.Loop() {
+		log.Info("msg", "k", 42)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/powerman/structlog"
+)
+
+// Errors.
+var (
+	errBadPredicate = errors.New("bad predicate, want KEY OP VALUE where OP is one of: = != < <= > >= ~")
+	errBadTime      = errors.New("bad time, want RFC3339, \"2006-01-02 15:04:05\" or duration")
+)
+
+// Operators sorted to match longest first.
+var operators = []string{"!=", "<=", ">=", "=", "<", ">", "~"} //nolint:gochecknoglobals // Const.
+
+// predicate matches value of key using operator.
+type predicate struct {
+	key string
+	op  string
+	val string
+	re  *regexp.Regexp // For "~".
+}
+
+// parsePredicate parses "KEY OP VALUE", like "user_id=42" or
+// "latency>500ms".
+func parsePredicate(s string) (*predicate, error) {
+	i := strings.IndexAny(s, "!=<>~")
+	if i <= 0 || strings.ContainsAny(s[:i], " \t") {
+		return nil, fmt.Errorf("%w: %q", errBadPredicate, s)
+	}
+	p := &predicate{key: s[:i]}
+	for _, op := range operators {
+		if strings.HasPrefix(s[i:], op) {
+			p.op, p.val = op, s[i+len(op):]
+			break
+		}
+	}
+	if p.op == "" {
+		return nil, fmt.Errorf("%w: %q", errBadPredicate, s)
+	}
+	if p.op == "~" {
+		re, err := regexp.Compile(p.val)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", s, err)
+		}
+		p.re = re
+	}
+	return p, nil
+}
+
+// match reports is rec matches p. Records without p.key match only "!=".
+//
+// Values are compared as durations, numbers or times (RFC3339) if both
+// values can be parsed as such, otherwise as strings. Integer compared
+// with duration is a number of nanoseconds.
+func (p *predicate) match(rec *structlog.Record) bool {
+	v, ok := rec.Get(p.key)
+	if !ok {
+		return p.op == "!="
+	}
+	s := fmt.Sprint(v)
+	if p.op == "~" {
+		return p.re.MatchString(s)
+	}
+	c := compare(s, p.val)
+	switch p.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// compare compares a and b as durations, numbers, times or strings.
+// Integer compared with duration is a duration in nanoseconds (as output
+// by structlog with SetTypedJSON).
+func compare(a, b string) int {
+	if da, ok := parseDuration(a, b); ok {
+		if db, ok := parseDuration(b, a); ok {
+			return cmp.Compare(da, db)
+		}
+	}
+	if fa, err := strconv.ParseFloat(a, 64); err == nil {
+		if fb, err := strconv.ParseFloat(b, 64); err == nil {
+			return cmp.Compare(fa, fb)
+		}
+	}
+	if ta, err := time.Parse(time.RFC3339Nano, a); err == nil {
+		if tb, err := time.Parse(time.RFC3339Nano, b); err == nil {
+			return ta.Compare(tb)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// parseDuration parses s as a duration or, if other is a duration, as
+// an integer number of nanoseconds.
+func parseDuration(s, other string) (time.Duration, bool) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, true
+	}
+	if _, err := time.ParseDuration(other); err != nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return time.Duration(n), err == nil
+}
+
+// filter contains all conditions used to select records.
+type filter struct {
+	level      structlog.Level
+	units      []string
+	since      time.Time
+	until      time.Time
+	message    *regexp.Regexp
+	predicates []*predicate
+}
+
+// match reports is rec matches all conditions of f.
+func (f *filter) match(rec *structlog.Record) bool {
+	switch {
+	case rec.Level < f.level:
+		return false
+	case len(f.units) > 0 && !slices.Contains(f.units, rec.Unit):
+		return false
+	case !f.since.IsZero() && rec.Time.Before(f.since):
+		return false
+	case !f.until.IsZero() && !rec.Time.Before(f.until):
+		return false
+	case f.message != nil && !f.message.MatchString(fmt.Sprint(rec.Message)):
+		return false
+	}
+	for _, p := range f.predicates {
+		if !p.match(rec) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTimeArg parses time given as RFC3339, as local "2006-01-02
+// 15:04:05" (seconds are optional) or as duration before now.
+func parseTimeArg(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", errBadTime, s)
+}
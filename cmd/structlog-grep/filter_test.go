@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestParsePredicate(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	tests := []struct {
+		s    string
+		want *predicate
+	}{
+		{"user_id=42", &predicate{key: "user_id", op: "=", val: "42"}},
+		{"k!=", &predicate{key: "k", op: "!=", val: ""}},
+		{"latency>=1s", &predicate{key: "latency", op: ">=", val: "1s"}},
+		{"n<=a=b", &predicate{key: "n", op: "<=", val: "a=b"}},
+		{"n<5", &predicate{key: "n", op: "<", val: "5"}},
+		{"n>5", &predicate{key: "n", op: ">", val: "5"}},
+	}
+	for _, tc := range tests {
+		p, err := parsePredicate(tc.s)
+		t.Nil(err, tc.s)
+		t.DeepEqual(p, tc.want, tc.s)
+	}
+	for _, s := range []string{"=42", "k!42", "a b=1"} {
+		_, err := parsePredicate(s)
+		t.Err(err, errBadPredicate, s)
+	}
+	_, err := parsePredicate("_m~(")
+	t.Match(err, "missing closing")
+}
+
+func TestPredicateMatch(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	rec := &structlog.Record{Keyvals: []any{
+		"user_id", int64(42),
+		"latency", "600ms",
+		"ratio", 0.5,
+		"at", "2020-01-02T03:04:05Z",
+		"name", "bob",
+		"typed", int64(1500000000),
+	}}
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"user_id=42", true},
+		{"user_id=42.0", true},
+		{"user_id!=42", false},
+		{"user_id>41", true},
+		{"user_id<=41", false},
+		{"latency>500ms", true},
+		{"latency<0.5s", false},
+		{"latency>=600ms", true},
+		{"ratio<1", true},
+		{"typed>500ms", true},
+		{"typed=1.5s", true},
+		{"typed<1s", false},
+		{"typed>1000", true},
+		{"at>2020-01-01T00:00:00Z", true},
+		{"at<2020-01-02T04:04:05+01:00", false},
+		{"name=bob", true},
+		{"name>alice", true},
+		{"name~^b", true},
+		{"name~^a", false},
+		{"missing=", false},
+		{"missing!=1", true},
+	}
+	for _, tc := range tests {
+		p, err := parsePredicate(tc.s)
+		t.Nil(err, tc.s)
+		t.Equal(p.match(rec), tc.want, tc.s)
+	}
+}
+
+func TestParseTimeArg(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"15m", now.Add(-15 * time.Minute)},
+		{"2020-01-02T03:04:05Z", now},
+		{"2020-01-02 03:04:05", time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)},
+		{"2020-01-02 03:04", time.Date(2020, 1, 2, 3, 4, 0, 0, time.Local)},
+		{"2020-01-02", time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tc := range tests {
+		got, err := parseTimeArg(tc.s, now)
+		t.Nil(err, tc.s)
+		t.True(got.Equal(tc.want), tc.s)
+	}
+	_, err := parseTimeArg("yesterday", now)
+	t.Err(err, errBadTime)
+}
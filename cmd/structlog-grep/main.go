@@ -0,0 +1,225 @@
+// Command structlog-grep filters log records output by structlog in JSON
+// format.
+//
+// Usage:
+//
+//	structlog-grep [flags] [predicate ...] [file ...]
+//
+// Flags may be given after predicates and files too, use "--" to stop
+// flags parsing.
+//
+// It reads from stdin if no files given (or file is "-"). Lines which
+// are not JSON objects are skipped.
+//
+// Predicate is KEY OP VALUE, where OP is one of: = != < <= > >= ~ (regexp
+// match). Values are compared as durations, numbers or RFC3339 times if
+// both values can be parsed as such, otherwise as strings. Integer
+// compared with duration is a number of nanoseconds (as output by
+// structlog with SetTypedJSON). Records without KEY match only "!=".
+// Examples: user_id=42, latency>500ms, _u~^http. Argument with OP is
+// considered a predicate, use "./a=b" to read file "a=b".
+//
+// Flags:
+//
+//	-level LEVEL        output only records with LEVEL or higher (default trc)
+//	-unit UNIT,...      output only records with given units
+//	-since TIME         output only records at TIME or later
+//	-until TIME         output only records before TIME
+//	-m REGEXP           output only records with message matching REGEXP
+//	-format FORMAT      output format: json (input line as is), text, logfmt (default json)
+//	-color MODE         one of: auto, never, always (default auto), for text format
+//	-time-format FORMAT format of input KeyTime (default structlog.DefaultTimeFormat)
+//
+// TIME is RFC3339, local "2006-01-02 15:04:05" or duration before now
+// (like 15m).
+//
+// Example:
+//
+//	kubectl logs deploy/app | structlog-grep -level WRN -since 1h 'latency>500ms' -format text
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/powerman/structlog"
+	"github.com/powerman/structlog/internal/jsonlog"
+)
+
+func main() {
+	var (
+		level      = structlog.TRC
+		format     = structlog.JSON
+		color      = structlog.DefaultColor
+		units      = flag.String("unit", "", "output only records with given comma-separated `units`")
+		since      = flag.String("since", "", "output only records at `time` or later")
+		until      = flag.String("until", "", "output only records before `time`")
+		message    = flag.String("m", "", "output only records with message matching `regexp`")
+		timeFormat = flag.String("time-format", structlog.DefaultTimeFormat, "`format` of input KeyTime")
+	)
+	flag.Var(&level, "level", "output only records with `level` or higher")
+	flag.Var(&format, "format", "output `format`: json (input line as is), text, logfmt")
+	flag.Var(&color, "color", "colorize text output `mode`: auto, never, always")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [predicate ...] [file ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	args, err := parseArgs(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		os.Exit(2) //nolint:mnd // Same as flag.ExitOnError.
+	}
+
+	g := &grep{
+		w:          bufio.NewWriter(os.Stdout),
+		timeFormat: *timeFormat,
+		filter:     filter{level: level},
+	}
+	if format != structlog.JSON {
+		g.f = structlog.New().SetOutput(os.Stdout).SetLogFormat(format).SetColor(color).Formatter()
+	}
+	files, err := g.setup(*units, *since, *until, *message, args, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2) //nolint:mnd // Same as flag.ExitOnError.
+	}
+	err = g.run(files)
+	err = errors.Join(err, g.w.Flush())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// parseArgs parses flags in args using fs and returns positional args.
+// Unlike fs.Parse it allows flags after positional args, until "--".
+func parseArgs(fs *flag.FlagSet, args []string) (positional []string, err error) {
+	for {
+		err = fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+type grep struct {
+	w          *bufio.Writer
+	f          structlog.Formatter // Nil to output input lines as is.
+	timeFormat string
+	filter     filter
+	buf        []byte
+}
+
+// setup initializes g.filter and returns files from args.
+func (g *grep) setup(units, since, until, message string, args []string, now time.Time) (files []string, err error) {
+	for unit := range strings.SplitSeq(units, ",") {
+		if unit = strings.TrimSpace(unit); unit != "" {
+			g.filter.units = append(g.filter.units, unit)
+		}
+	}
+	if g.filter.since, err = parseTimeArg(since, now); err != nil {
+		return nil, err
+	}
+	if g.filter.until, err = parseTimeArg(until, now); err != nil {
+		return nil, err
+	}
+	if message != "" {
+		if g.filter.message, err = regexp.Compile(message); err != nil {
+			return nil, err
+		}
+	}
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "!=<>~") {
+			files = append(files, arg)
+			continue
+		}
+		p, err := parsePredicate(arg)
+		if err != nil {
+			return nil, err
+		}
+		g.filter.predicates = append(g.filter.predicates, p)
+	}
+	return files, nil
+}
+
+// run processes files (stdin if empty) one by one.
+func (g *grep) run(files []string) error {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	var errs []error
+	for _, name := range files {
+		errs = append(errs, g.processFile(name))
+	}
+	return errors.Join(errs...)
+}
+
+func (g *grep) processFile(name string) error {
+	if name == "-" {
+		return g.process(os.Stdin)
+	}
+	f, err := os.Open(name) //nolint:gosec // By design.
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return g.process(f)
+}
+
+// process outputs matching lines from r.
+func (g *grep) process(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if err := g.processLine(line); err != nil {
+				return err
+			}
+		}
+		if br.Buffered() == 0 { // Do not delay output while waiting for input.
+			if err := g.w.Flush(); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// processLine outputs line if it's a JSON object which matches g.filter.
+func (g *grep) processLine(line []byte) error {
+	rec, err := jsonlog.Parse(line, g.timeFormat)
+	if err != nil || !g.filter.match(rec) {
+		return nil //nolint:nilerr // Skip non-JSON lines.
+	}
+	if g.f == nil {
+		g.buf = append(g.buf[:0], line...)
+		if !bytes.HasSuffix(g.buf, []byte("\n")) {
+			g.buf = append(g.buf, '\n')
+		}
+	} else {
+		g.buf = append(g.f.AppendRecord(g.buf[:0], rec), '\n')
+	}
+	_, err = g.w.Write(g.buf)
+	return err
+}
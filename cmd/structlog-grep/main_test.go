@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestMain(m *testing.M) {
+	time.Local = time.UTC
+	check.TestMain(m)
+}
+
+const input = `` +
+	`{"_t":"2020-01-02T03:04:05Z","_a":"app","_p":"1","_l":"inf","_u":"http","_m":"request","user_id":42,"latency":"600ms"}` + "\n" +
+	`{"_t":"2020-01-02T03:05:05Z","_a":"app","_p":"1","_l":"dbg","_u":"http","_m":"request","user_id":42,"latency":"1s"}` + "\n" +
+	`not json` + "\n" +
+	`{"_t":"2020-01-02T03:06:05Z","_a":"app","_p":"1","_l":"ERR","_u":"db","_m":"failed to connect","user_id":42}` + "\n" +
+	`{"_t":"2020-01-02T03:07:05Z","_a":"app","_p":"1","_l":"WRN","_u":"http","_m":"request","user_id":7,"latency":"2s"}`
+
+func TestProcess(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	lines := strings.Split(input, "\n")
+	now := time.Date(2020, 1, 2, 3, 10, 0, 0, time.UTC)
+	tests := []struct {
+		level        structlog.Level
+		units        string
+		since, until string
+		message      string
+		args         []string
+		want         []string
+	}{
+		{structlog.TRC, "", "", "", "", nil, []string{lines[0], lines[1], lines[3], lines[4]}},
+		{structlog.INF, "", "", "", "", nil, []string{lines[0], lines[3], lines[4]}},
+		{structlog.TRC, "db, grpc", "", "", "", nil, []string{lines[3]}},
+		{structlog.TRC, "", "5m", "2020-01-02T03:07:05Z", "", nil, []string{lines[1], lines[3]}},
+		{structlog.TRC, "", "", "", "^fail", nil, []string{lines[3]}},
+		{structlog.TRC, "", "", "", "", []string{"user_id=42", "latency>500ms"}, []string{lines[0], lines[1]}},
+		{structlog.INF, "http", "", "", "", []string{"latency>=1s"}, []string{lines[4]}},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		g := &grep{w: bufio.NewWriter(&buf), timeFormat: structlog.DefaultTimeFormat, filter: filter{level: tc.level}}
+		files, err := g.setup(tc.units, tc.since, tc.until, tc.message, tc.args, now)
+		t.Nil(err)
+		t.Len(files, 0)
+		t.Nil(g.process(strings.NewReader(input)))
+		t.Equal(buf.String(), strings.Join(tc.want, "\n")+"\n", tc)
+	}
+}
+
+func TestProcessText(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	g := &grep{
+		w:          bufio.NewWriter(&buf),
+		f:          structlog.New().SetOutput(&buf).Formatter(),
+		timeFormat: structlog.DefaultTimeFormat,
+	}
+	files, err := g.setup("", "", "", "", []string{"_l=ERR", "file.log"}, time.Now())
+	t.Nil(err)
+	t.DeepEqual(files, []string{"file.log"})
+	t.Nil(g.process(strings.NewReader(input)))
+	t.Equal(buf.String(), "Jan  2 03:06:05.000000 app[1] ERR db: `failed to connect` user_id=42\n")
+}
+
+func TestSetupErrors(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	g := &grep{}
+	_, err := g.setup("", "bad", "", "", nil, time.Now())
+	t.Err(err, errBadTime)
+	_, err = g.setup("", "", "bad", "", nil, time.Now())
+	t.Err(err, errBadTime)
+	_, err = g.setup("", "", "", "(", nil, time.Now())
+	t.Match(err, "missing closing")
+	_, err = g.setup("", "", "", "", []string{"=1"}, time.Now())
+	t.Err(err, errBadPredicate)
+}
+
+func TestProcessTypedJSON(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	const line = `{"_t":"2020-01-02T03:04:05Z","_l":"inf","_m":"request","latency":1500000000}` + "\n"
+	var buf bytes.Buffer
+	g := &grep{w: bufio.NewWriter(&buf), timeFormat: structlog.DefaultTimeFormat}
+	_, err := g.setup("", "", "", "", []string{"latency>500ms", "latency<2s"}, time.Now())
+	t.Nil(err)
+	t.Nil(g.process(strings.NewReader(line)))
+	t.Equal(buf.String(), line)
+}
+
+func TestParseArgs(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	tests := []struct {
+		args  []string
+		level structlog.Level
+		want  []string
+	}{
+		{nil, structlog.TRC, nil},
+		{[]string{"-level", "WRN", "a>1", "-"}, structlog.WRN, []string{"a>1", "-"}},
+		{[]string{"-level", "WRN", "-since", "1h", "latency>500ms", "-format", "text"}, structlog.WRN, []string{"latency>500ms"}},
+		{[]string{"a>1", "-level", "ERR", "file", "-format", "text", "b=2"}, structlog.ERR, []string{"a>1", "file", "b=2"}},
+		{[]string{"a>1", "--", "-level", "ERR"}, structlog.TRC, []string{"a>1", "-level", "ERR"}},
+	}
+	for _, tc := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		level, format := structlog.TRC, structlog.JSON
+		fs.Var(&level, "level", "")
+		fs.Var(&format, "format", "")
+		fs.String("since", "", "")
+		args, err := parseArgs(fs, tc.args)
+		t.Nil(err, tc.args)
+		t.DeepEqual(args, tc.want, tc.args)
+		t.Equal(level, tc.level, tc.args)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	_, err := parseArgs(fs, []string{"a>1", "-bad"})
+	t.Match(err, "not defined")
+}
@@ -0,0 +1,161 @@
+// Command structlog-pretty reads log records output by structlog in JSON
+// format and outputs them in Text format using structlog.DefaultLogger's
+// layout.
+//
+// Usage:
+//
+//	structlog-pretty [flags] [file ...]
+//
+// It reads from stdin if no files given (or file is "-"). Lines which
+// are not JSON objects are output as is.
+//
+// Flags:
+//
+//	-level LEVEL        output only records with LEVEL or higher (default trc)
+//	-color MODE         one of: auto, never, always (default auto)
+//	-time-format FORMAT format of input KeyTime (default structlog.DefaultTimeFormat)
+//	-f                  wait for new lines at end of file, like "tail -f"
+//
+// Example:
+//
+//	kubectl logs -f deploy/app | structlog-pretty -level inf
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/powerman/structlog"
+	"github.com/powerman/structlog/internal/jsonlog"
+)
+
+const followInterval = 200 * time.Millisecond
+
+func main() {
+	var (
+		level      = structlog.TRC
+		color      = structlog.DefaultColor
+		timeFormat = flag.String("time-format", structlog.DefaultTimeFormat, "`format` of input KeyTime")
+		follow     = flag.Bool("f", false, "wait for new lines at end of file")
+	)
+	flag.Var(&level, "level", "output only records with `level` or higher")
+	flag.Var(&color, "color", "colorize output `mode`: auto, never, always")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [file ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	p := &prettifier{
+		w:          os.Stdout,
+		f:          structlog.New().SetOutput(os.Stdout).SetColor(color).Formatter(),
+		level:      level,
+		timeFormat: *timeFormat,
+	}
+	if err := p.run(flag.Args(), *follow); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+type prettifier struct {
+	mu         sync.Mutex
+	w          io.Writer
+	f          structlog.Formatter
+	level      structlog.Level
+	timeFormat string
+	buf        []byte
+}
+
+// run processes files (stdin if empty). In follow mode files are
+// processed concurrently, otherwise one by one.
+func (p *prettifier) run(files []string, follow bool) error {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	for i, name := range files {
+		process := func() { errs[i] = p.processFile(name, follow) }
+		if follow {
+			wg.Go(process)
+		} else {
+			process()
+		}
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (p *prettifier) processFile(name string, follow bool) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name) //nolint:gosec // By design.
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if follow {
+		r = &followReader{r: r}
+	}
+	return p.process(r)
+}
+
+// process outputs all lines from r.
+func (p *prettifier) process(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if err := p.processLine(line); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// processLine outputs line in Text format if it's a JSON object.
+func (p *prettifier) processLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rec, err := jsonlog.Parse(line, p.timeFormat)
+	switch {
+	case err != nil:
+		p.buf = append(p.buf[:0], line...)
+		if line[len(line)-1] != '\n' {
+			p.buf = append(p.buf, '\n')
+		}
+	case rec.Level < p.level:
+		return nil
+	default:
+		p.buf = append(p.f.AppendRecord(p.buf[:0], rec), '\n')
+	}
+	_, err = p.w.Write(p.buf)
+	return err
+}
+
+// followReader waits for new data instead of returning io.EOF.
+type followReader struct{ r io.Reader }
+
+func (f *followReader) Read(buf []byte) (int, error) {
+	for {
+		n, err := f.r.Read(buf)
+		if n > 0 || !errors.Is(err, io.EOF) {
+			return n, err
+		}
+		time.Sleep(followInterval)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestMain(m *testing.M) {
+	time.Local = time.UTC
+	check.TestMain(m)
+}
+
+func TestProcess(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	p := &prettifier{
+		w:          &buf,
+		f:          structlog.New().SetOutput(&buf).Formatter(),
+		level:      structlog.INF,
+		timeFormat: structlog.DefaultTimeFormat,
+	}
+	t.Nil(p.process(strings.NewReader(`` +
+		`{"_t":"Jan  2 03:04:05.123456","_a":"app","_p":"42","_l":"inf","_u":"db","_m":"started","port":"8080","_f":"main.main","_s":"main.go:8"}` + "\n" +
+		`{"_t":"Jan  2 03:04:05.123456","_a":"app","_p":"42","_l":"dbg","_m":"skipped"}` + "\n" +
+		`panic: oops` + "\n" +
+		`{"_t":"Jan  2 03:04:05.123456","_a":"app","_p":42,"_l":"ERR","_m":"failed","err":"EOF","__":"stack"}`)))
+	t.Equal(buf.String(), ""+
+		"Jan  2 03:04:05.123456 app[42] inf db: `started` port=8080 \t@ main.main(main.go:8)\n"+
+		"panic: oops\n"+
+		"Jan  2 03:04:05.123456 app[42] ERR `failed` err=EOF\nstack\n")
+}
+
+func TestFollowReader(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "app.log")
+	t.Nil(os.WriteFile(name, []byte("line1\n"), 0o600))
+	f, err := os.Open(name) //nolint:gosec // False positive.
+	t.Nil(err)
+	defer f.Close()
+	r := &followReader{r: f}
+	buf := make([]byte, 16)
+	n, err := r.Read(buf)
+	t.Nil(err)
+	t.Equal(string(buf[:n]), "line1\n")
+
+	done := make(chan string)
+	go func() {
+		n, err := r.Read(buf)
+		t.Nil(err)
+		done <- string(buf[:n])
+	}()
+	select {
+	case <-done:
+		t.Fatal("Read returned before new data")
+	case <-time.After(2 * followInterval):
+	}
+	w, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0) //nolint:gosec // False positive.
+	t.Nil(err)
+	_, err = w.WriteString("line2\n")
+	t.Nil(err)
+	t.Nil(w.Close())
+	t.Equal(<-done, "line2\n")
+}
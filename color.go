@@ -0,0 +1,305 @@
+package structlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Color defines when Text format is colorized using ANSI escape
+// sequences. It implements [flag.Value], [encoding.TextMarshaler] and
+// [encoding.TextUnmarshaler], so it can be used with [flag.Var] and in
+// config structs.
+type Color byte
+
+// Color modes.
+const (
+	// ColorAuto colorizes output written to a terminal using SetOutput
+	// (or NewAsyncWriter), unless NO_COLOR environment variable is set.
+	ColorAuto Color = iota
+	ColorNever
+	ColorAlways
+)
+
+// Defaults.
+const (
+	DefaultColor        = ColorAuto
+	DefaultMessageWidth = 0
+)
+
+// ErrUnknownColor is returned by ParseColor.
+var ErrUnknownColor = errors.New("unknown color mode")
+
+// ANSI escape sequences used by Text format.
+const (
+	colorReset   = "\x1b[0m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorBoldRed = "\x1b[1;31m"
+	colorYellow  = "\x1b[33m"
+	colorCyan    = "\x1b[36m"
+)
+
+// ParseColor convert colorName (one of: auto, never, always) into Color
+// or returns ErrUnknownColor.
+func ParseColor(colorName string) (Color, error) {
+	switch strings.ToLower(colorName) {
+	case "auto":
+		return ColorAuto, nil
+	case "never":
+		return ColorNever, nil
+	case "always":
+		return ColorAlways, nil
+	default:
+		return ColorAuto, ErrUnknownColor
+	}
+}
+
+func (c Color) String() string {
+	switch c {
+	case ColorAuto:
+		return "auto"
+	case ColorNever:
+		return "never"
+	case ColorAlways:
+		return "always"
+	default:
+		return unknown
+	}
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+// It accepts same names as ParseColor.
+func (c *Color) UnmarshalText(text []byte) error {
+	return c.Set(string(text))
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+// It accepts JSON string with same names as ParseColor.
+func (c *Color) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, c)
+}
+
+// Set implements [flag.Value].
+// It accepts same names as ParseColor.
+func (c *Color) Set(colorName string) error {
+	color, err := ParseColor(colorName)
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, colorName)
+	}
+	*c = color
+	return nil
+}
+
+// SetColor changes when Text format will be colorized (default value is
+// DefaultColor).
+//
+// Colorized output has level highlighted (ERR and CRT are red, WRN is
+// yellow, INF and lower levels are dimmed), key names highlighted and
+// KeyFunc and KeySource dimmed.
+//
+// It doesn't creates a new logger, it returns l just for convenience.
+func (l *Logger) SetColor(color Color) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.color = &color
+	return l
+}
+
+// SetMessageWidth makes Text format pad log line after KeyMessage with
+// spaces up to width columns (default value is DefaultMessageWidth, 0
+// disables padding). This way keys output after message will be
+// vertically aligned in most log lines.
+//
+// Width is counted in runes from line start, ignoring color escape
+// sequences. Padding won't be added if nothing is output after message.
+//
+// It doesn't creates a new logger, it returns l just for convenience.
+func (l *Logger) SetMessageWidth(width int) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messageWidth = &width
+	return l
+}
+
+// useColor returns true if Text format should be colorized.
+//
+// mergeParent must be called before useColor.
+func (l *Logger) useColor() bool {
+	switch *l.color {
+	case ColorAlways:
+		return true
+	case ColorAuto:
+		return isTerminal(l.printer)
+	default:
+		return false
+	}
+}
+
+// isTerminal returns true if p writes to a terminal and NO_COLOR
+// environment variable was not set when p was created (or when standard
+// logger's output was changed, for default Printer).
+func isTerminal(p Printer) bool {
+	switch p := p.(type) {
+	case writerPrinter:
+		return p.tty
+	case stdLogPrinter:
+		return isStdLogTerminal()
+	case *AsyncPrinter:
+		return isTerminal(p.p)
+	default:
+		return false
+	}
+}
+
+// stdLogOutput caches writerPrinter for standard logger's output, it's
+// used to avoid checking is output a terminal on each log record.
+var stdLogOutput atomic.Pointer[writerPrinter] //nolint:gochecknoglobals // Cache.
+
+// isStdLogTerminal returns true if standard logger writes to a terminal.
+func isStdLogTerminal() bool {
+	f, ok := log.Writer().(*os.File)
+	if !ok {
+		return false
+	}
+	p := stdLogOutput.Load()
+	if p == nil || p.w != f {
+		wp := newWriterPrinter(f)
+		p = &wp
+		stdLogOutput.Store(p)
+	}
+	return p.tty
+}
+
+// isTerminalWriter returns true if w is a terminal and NO_COLOR
+// environment variable is not set.
+func isTerminalWriter(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminalFile(f)
+}
+
+// levelColor returns escape sequence used to colorize level.
+func levelColor(level Level) string {
+	switch {
+	case level >= CRT:
+		return colorBoldRed
+	case level >= ERR:
+		return colorRed
+	case level >= WRN:
+		return colorYellow
+	case level <= INF:
+		return colorDim
+	default:
+		return ""
+	}
+}
+
+// appendStyledKeyVal appends key and val formatted according to kf,
+// colorized according to key if color is true and sanitized (except
+// KeyStack) if sanitize is true.
+func (r *logRecord) appendStyledKeyVal(buf []byte, kf *keyFormat, key string, val any, color, sanitize bool) []byte {
+	sanitize = sanitize && key != KeyStack
+	switch {
+	case !color:
+		return kf.appendStyledKeyVal(buf, key, val, "", "", sanitize)
+	case key == KeyFunc || key == KeySource:
+		buf = append(buf, colorDim...)
+		buf = kf.appendStyledKeyVal(buf, key, val, "", "", sanitize)
+		return append(buf, colorReset...)
+	case key == KeyLevel:
+		return kf.appendStyledKeyVal(buf, key, val, "", levelColor(r.level), sanitize)
+	case key == KeyTime, key == KeyApp, key == KeyPID, key == KeyUnit, key == KeyMessage, key == KeyStack:
+		return kf.appendStyledKeyVal(buf, key, val, "", "", sanitize)
+	default:
+		return kf.appendStyledKeyVal(buf, key, val, colorCyan, "", sanitize)
+	}
+}
+
+// appendStyledKeyVal appends key and val formatted according to kf using
+// keyColor and valColor (if not empty) for key and val. If sanitize is
+// true then key and val will be sanitized using appendSanitized (or
+// appendSanitizedQuoted for %q).
+//
+// If kf needs fmt then whole output of kf will be colorized using
+// valColor and sanitized except tabs.
+func (kf *keyFormat) appendStyledKeyVal(buf []byte, key string, val any, keyColor, valColor string, sanitize bool) []byte {
+	if kf.parts == nil {
+		buf = append(buf, valColor...)
+		start := len(buf)
+		buf = fmt.Appendf(buf, kf.format, key, val)
+		if sanitize {
+			buf = appendSanitized(buf, start, true)
+		}
+		if valColor != "" {
+			buf = append(buf, colorReset...)
+		}
+		return buf
+	}
+	for i := range kf.parts {
+		p := &kf.parts[i]
+		if p.arg == 0 {
+			buf = append(buf, p.lit...)
+			continue
+		}
+		color := keyColor
+		if p.arg == 2 { //nolint:mnd // Value.
+			color = valColor
+		}
+		buf = append(buf, color...)
+		start := len(buf)
+		if p.arg == 1 {
+			buf = p.appendString(buf, key)
+		} else {
+			buf = p.appendArg(buf, val)
+		}
+		switch {
+		case sanitize && p.verb == 'q':
+			buf = appendSanitizedQuoted(buf, start)
+		case sanitize:
+			buf = appendSanitized(buf, start, false)
+		}
+		if color != "" {
+			buf = append(buf, colorReset...)
+		}
+	}
+	return buf
+}
+
+// textWidth returns amount of runes in b, excluding escape sequences.
+func textWidth(b []byte) int {
+	n := 0
+	for len(b) > 0 {
+		if b[0] == '\x1b' {
+			if i := bytes.IndexByte(b, 'm'); i != -1 {
+				b = b[i+1:]
+				continue
+			}
+		}
+		_, size := utf8.DecodeRune(b)
+		b = b[size:]
+		n++
+	}
+	return n
+}
+
+// appendPadding appends n spaces.
+func appendPadding(buf []byte, n int) []byte {
+	for range n {
+		buf = append(buf, ' ')
+	}
+	return buf
+}
@@ -0,0 +1,66 @@
+package structlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestColor(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := structlog.New().SetOutput(&buf).SetColor(structlog.ColorAlways)
+	log.Info("msg", "k", 1)
+	log.Warn("msg")
+	log.Err("msg")
+	log.Crit("msg")
+	log.SetColor(structlog.ColorAuto).Info("msg", "k", 1)
+	t.Equal(buf.String(), ""+
+		"structlog.test["+pid+"] \x1b[2minf\x1b[0m "+unit+": `msg` \x1b[36mk\x1b[0m=1\x1b[2m \t@ structlog_test.TestColor\x1b[0m\x1b[2m(color_test.go:17)\x1b[0m\n"+
+		"structlog.test["+pid+"] \x1b[33mWRN\x1b[0m "+unit+": `msg`\x1b[2m \t@ structlog_test.TestColor\x1b[0m\x1b[2m(color_test.go:18)\x1b[0m\n"+
+		"structlog.test["+pid+"] \x1b[31mERR\x1b[0m "+unit+": `msg`\x1b[2m \t@ structlog_test.TestColor\x1b[0m\x1b[2m(color_test.go:19)\x1b[0m\n"+
+		"structlog.test["+pid+"] \x1b[1;31mCRT\x1b[0m "+unit+": `msg`\x1b[2m \t@ structlog_test.TestColor\x1b[0m\x1b[2m(color_test.go:20)\x1b[0m\n"+
+		"structlog.test["+pid+"] inf "+unit+": `msg` k=1 \t@ structlog_test.TestColor(color_test.go:21)\n")
+}
+
+func TestMessageWidth(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := structlog.New(structlog.KeyApp, "app", structlog.KeyPID, 1, structlog.KeyUnit, "u",
+		structlog.KeyFunc, nil, structlog.KeySource, nil).
+		SetOutput(&buf).SetMessageWidth(30)
+	log.Info("short", "k", 1)
+	log.Info("longer message", "k", 2)
+	log.Info("very long message which does not fit", "k", 3)
+	log.Info("ünïcode", "k", 4)
+	log.Info("nothing after")
+	log.SetColor(structlog.ColorAlways).Info("short", "k", 5)
+	t.Equal(buf.String(), ""+
+		"app[1] inf u: `short`          k=1\n"+
+		"app[1] inf u: `longer message` k=2\n"+
+		"app[1] inf u: `very long message which does not fit` k=3\n"+
+		"app[1] inf u: `ünïcode`        k=4\n"+
+		"app[1] inf u: `nothing after`\n"+
+		"app[1] \x1b[2minf\x1b[0m u: `short`          \x1b[36mk\x1b[0m=5\n")
+}
+
+func TestParseColor(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	for _, c := range []structlog.Color{structlog.ColorAuto, structlog.ColorNever, structlog.ColorAlways} {
+		var got structlog.Color
+		t.Nil(got.Set(c.String()))
+		t.Equal(got, c)
+	}
+	_, err := structlog.ParseColor("sometimes")
+	t.Err(err, structlog.ErrUnknownColor)
+	var c structlog.Color
+	t.Match(c.UnmarshalText([]byte("sometimes")), `unknown color mode: "sometimes"`)
+	t.Nil(c.UnmarshalJSON([]byte(`"ALWAYS"`)))
+	t.Equal(c, structlog.ColorAlways)
+}
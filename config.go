@@ -0,0 +1,241 @@
+package structlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+)
+
+// ErrTooLate is returned by Config.Apply if PrefixKeys or SuffixKeys
+// should be changed after logger was used.
+var ErrTooLate = errors.New("too late to reconfigure prefixKeys/suffixKeys")
+
+// configFiles contains files opened for Config.Output. They are never
+// closed (loggers using them may still exist) but reused by next Apply
+// with same Output.
+var configFiles struct { //nolint:gochecknoglobals // Cache.
+	mu     sync.Mutex
+	byName map[string]*os.File
+}
+
+// Config contains Logger settings. It can be loaded from JSON config file
+// (field names are also suitable for YAML) and applied to a logger using
+// Apply. Use Logger.Snapshot to get effective settings of a logger.
+//
+// Empty fields won't change logger's settings, except PrefixKeys and
+// SuffixKeys which are changed unless nil.
+type Config struct {
+	// Printer is used instead of Output if not nil.
+	Printer Printer `json:"-" yaml:"-"`
+	// Output is one of: "log" (standard logger), "stdout", "stderr"
+	// or file name (log records will be appended to the file, which is
+	// opened once and kept open for all Apply calls with same Output).
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+	// Format is one of: "text", "json", "logfmt".
+	Format *Format `json:"format,omitempty" yaml:"format,omitempty"`
+	// Level is a level name, see ParseLevel.
+	Level *Level `json:"level,omitempty" yaml:"level,omitempty"`
+	// Color is one of: "auto", "never", "always", see SetColor.
+	Color *Color `json:"color,omitempty" yaml:"color,omitempty"`
+	// UnitLevels contains level name for units, see SetUnitLevel.
+	UnitLevels     map[string]Level  `json:"unit_levels,omitempty"     yaml:"unit_levels,omitempty"`
+	KeyValFormat   string            `json:"key_val_format,omitempty"  yaml:"key_val_format,omitempty"`
+	TimeFormat     string            `json:"time_format,omitempty"     yaml:"time_format,omitempty"`
+	TimeValFormat  string            `json:"time_val_format,omitempty" yaml:"time_val_format,omitempty"`
+	TypedJSON      *bool             `json:"typed_json,omitempty"      yaml:"typed_json,omitempty"`
+	MessageWidth   *int              `json:"message_width,omitempty"   yaml:"message_width,omitempty"`
+	Sanitize       *bool             `json:"sanitize,omitempty"        yaml:"sanitize,omitempty"`
+	PrefixKeys     []string          `json:"prefix_keys"               yaml:"prefix_keys"`
+	SuffixKeys     []string          `json:"suffix_keys"               yaml:"suffix_keys"`
+	KeysFormat     map[string]string `json:"keys_format,omitempty"     yaml:"keys_format,omitempty"`
+	DefaultKeyvals map[string]any    `json:"default_keyvals,omitempty" yaml:"default_keyvals,omitempty"`
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+//
+// It returns error on unknown fields and invalid Format, Color and levels.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type config Config // Avoid recursion.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var cfg config
+	if err := dec.Decode(&cfg); err != nil {
+		return err
+	}
+	*c = Config(cfg)
+	return nil
+}
+
+func (c *Config) validate(l *Logger) error {
+	if c.PrefixKeys != nil || c.SuffixKeys != nil {
+		l.mu.RLock()
+		used := l.parent == nil
+		l.mu.RUnlock()
+		if used {
+			return ErrTooLate
+		}
+	}
+	if c.Format != nil && c.Format.String() == unknown {
+		return fmt.Errorf("%w: %d", ErrUnknownFormat, *c.Format)
+	}
+	if c.Color != nil && c.Color.String() == unknown {
+		return fmt.Errorf("%w: %d", ErrUnknownColor, *c.Color)
+	}
+	return nil
+}
+
+// Apply changes l's settings using non-empty fields of c.
+//
+// Like SetPrefixKeys it must be called before l is used, otherwise it
+// returns ErrTooLate if PrefixKeys or SuffixKeys are not nil.
+// If c has invalid values or Output file can't be opened then l won't be
+// changed.
+func (c *Config) Apply(l *Logger) error {
+	if err := c.validate(l); err != nil {
+		return err
+	}
+	printer, err := c.printer()
+	if err != nil {
+		return err
+	}
+
+	if printer != nil {
+		l.SetPrinter(printer)
+	}
+	if c.Format != nil {
+		l.SetLogFormat(*c.Format)
+	}
+	if c.Level != nil {
+		l.SetLogLevel(*c.Level)
+	}
+	for unit, level := range c.UnitLevels {
+		l.SetUnitLevel(unit, level)
+	}
+	if c.KeyValFormat != "" {
+		l.SetKeyValFormat(c.KeyValFormat)
+	}
+	if c.TimeFormat != "" {
+		l.SetTimeFormat(c.TimeFormat)
+	}
+	if c.TimeValFormat != "" {
+		l.SetTimeValFormat(c.TimeValFormat)
+	}
+	if c.TypedJSON != nil {
+		l.SetTypedJSON(*c.TypedJSON)
+	}
+	if c.Color != nil {
+		l.SetColor(*c.Color)
+	}
+	if c.MessageWidth != nil {
+		l.SetMessageWidth(*c.MessageWidth)
+	}
+	if c.Sanitize != nil {
+		l.SetSanitize(*c.Sanitize)
+	}
+	if c.PrefixKeys != nil {
+		l.SetPrefixKeys(c.PrefixKeys...)
+	}
+	if c.SuffixKeys != nil {
+		l.SetSuffixKeys(c.SuffixKeys...)
+	}
+	if len(c.KeysFormat) > 0 {
+		l.SetKeysFormat(c.KeysFormat)
+	}
+	const pairSize = 2
+	keyvals := make([]any, 0, len(c.DefaultKeyvals)*pairSize)
+	for _, k := range slices.Sorted(maps.Keys(c.DefaultKeyvals)) {
+		keyvals = append(keyvals, k, c.DefaultKeyvals[k])
+	}
+	l.SetDefaultKeyvals(keyvals...)
+	return nil
+}
+
+// printer returns c.Printer or printer for c.Output or nil if both are
+// empty.
+func (c *Config) printer() (Printer, error) {
+	if c.Printer != nil || c.Output == "" {
+		return c.Printer, nil
+	}
+	switch c.Output {
+	case "log":
+		return stdLogPrinter{}, nil
+	case "stdout":
+		return newWriterPrinter(os.Stdout), nil
+	case "stderr":
+		return newWriterPrinter(os.Stderr), nil
+	}
+	configFiles.mu.Lock()
+	defer configFiles.mu.Unlock()
+	f := configFiles.byName[c.Output]
+	if f == nil {
+		const perm = 0o644
+		var err error
+		f, err = os.OpenFile(c.Output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm) //nolint:gosec // By design.
+		if err != nil {
+			return nil, err
+		}
+		if configFiles.byName == nil {
+			configFiles.byName = make(map[string]*os.File)
+		}
+		configFiles.byName[c.Output] = f
+	}
+	return newWriterPrinter(f), nil
+}
+
+// Snapshot returns effective settings of l, including inherited ones.
+//
+// Output will be set only if l uses standard logger or l's output was set
+// using Config.Output or SetOutput with [os.File].
+func (l *Logger) Snapshot() Config {
+	l.enabled(DBG) // Call mergeParent.
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	lv := l.levels.Load()
+	format := *l.format
+	level := lv.level.Level()
+	color := *l.color
+	typedJSON := *l.typedJSON
+	messageWidth := *l.messageWidth
+	sanitize := *l.sanitize
+	c := Config{
+		Printer:        l.printer,
+		Format:         &format,
+		Level:          &level,
+		Color:          &color,
+		UnitLevels:     make(map[string]Level),
+		KeyValFormat:   *l.keyValFormat,
+		TimeFormat:     *l.timeFormat,
+		TimeValFormat:  *l.timeValFormat,
+		TypedJSON:      &typedJSON,
+		MessageWidth:   &messageWidth,
+		Sanitize:       &sanitize,
+		PrefixKeys:     slices.Clone(l.prefixKeys),
+		SuffixKeys:     slices.Clone(l.suffixKeys),
+		KeysFormat:     maps.Clone(l.keysFormat),
+		DefaultKeyvals: maps.Clone(l.defaultKeyvals),
+	}
+	for unit, v := range lv.units.vars() {
+		c.UnitLevels[unit] = v.Level()
+	}
+	if _, ok := l.printer.(stdLogPrinter); ok {
+		c.Output = "log"
+	} else if p, ok := l.printer.(writerPrinter); ok {
+		if f, ok := p.w.(*os.File); ok {
+			switch f {
+			case os.Stdout:
+				c.Output = "stdout"
+			case os.Stderr:
+				c.Output = "stderr"
+			default:
+				c.Output = f.Name()
+			}
+		}
+	}
+	return c
+}
@@ -0,0 +1,134 @@
+package structlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestConfig(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var cfg structlog.Config
+	t.Nil(json.Unmarshal([]byte(`{
+		"format": "logfmt",
+		"level": "INF",
+		"unit_levels": {"db": "debug"},
+		"time_format": "15:04",
+		"color": "Always",
+		"message_width": 20,
+		"sanitize": true,
+		"prefix_keys": ["_l", "_u"],
+		"suffix_keys": [],
+		"default_keyvals": {"app": "demo", "_u": "main"}
+	}`), &cfg))
+
+	var buf bytes.Buffer
+	cfg.Printer = structlog.PrinterFunc(func(v ...any) { buf.WriteString(v[0].(string) + "\n") })
+	log := structlog.NewZeroLogger()
+	t.Nil(cfg.Apply(log))
+	log.Debug("skip")
+	log.Info("shown", "k", 1)
+	log.Debug("shown", structlog.KeyUnit, "db")
+	t.Equal(buf.String(), ""+
+		`_t=02:04 _l=inf _u=main _m=shown k=1`+"\n"+
+		`_t=02:04 _l=dbg _u=db _m=shown`+"\n")
+
+	snap := log.Snapshot()
+	format, level, color := structlog.Logfmt, structlog.INF, structlog.ColorAlways
+	width, sanitize := 20, true
+	t.NotNil(snap.Printer)
+	snap.Printer = nil
+	t.DeepEqual(snap, structlog.Config{
+		Format:        &format,
+		Level:         &level,
+		Color:         &color,
+		UnitLevels:    map[string]structlog.Level{"db": structlog.DBG},
+		KeyValFormat:  structlog.DefaultKeyValFormat,
+		TimeFormat:    "15:04",
+		TimeValFormat: structlog.DefaultTimeValFormat,
+		TypedJSON:     new(bool),
+		MessageWidth:  &width,
+		Sanitize:      &sanitize,
+		PrefixKeys:    []string{"_l", "_u"},
+		SuffixKeys:    []string{},
+		KeysFormat:    map[string]string{},
+		DefaultKeyvals: map[string]any{
+			"app":               "demo",
+			structlog.KeyUnit:   "main",
+			structlog.KeyFunc:   "???",
+			structlog.KeySource: "???",
+		},
+	})
+
+	child := log.New().SetTimeValFormat(time.DateOnly)
+	snap = child.Snapshot()
+	t.Equal(*snap.Level, structlog.INF)
+	t.Equal(snap.TimeValFormat, time.DateOnly)
+	t.Equal(snap.DefaultKeyvals["app"], "demo")
+}
+
+func TestConfigOutput(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "app.log")
+	format := structlog.JSON
+	cfg := structlog.Config{Output: name, Format: &format, PrefixKeys: []string{}}
+	log := structlog.NewZeroLogger()
+	t.Nil(cfg.Apply(log))
+	log.Info("msg")
+	buf, err := os.ReadFile(name) //nolint:gosec // False positive.
+	t.Nil(err)
+	t.Equal(string(buf), `{"_t":"Jan  2 02:04:05.123456","_l":"inf","_m":"msg"}`+"\n")
+	t.Equal(log.Snapshot().Output, name)
+
+	t.Equal(structlog.New().SetOutput(os.Stderr).Snapshot().Output, "stderr")
+	t.Equal(structlog.New().Snapshot().Output, "log")
+	t.Equal(structlog.New().SetPrinter(structlog.PrinterFunc(func(...any) {})).Snapshot().Output, "")
+	t.NotNil((&structlog.Config{Output: t.TempDir()}).Apply(log))
+
+	log2 := structlog.NewZeroLogger()
+	t.Nil((&structlog.Config{Output: name}).Apply(log2))
+	t.Equal(log2.Snapshot().Printer, log.Snapshot().Printer, "file is reused")
+}
+
+func TestConfigErrors(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	tests := []struct {
+		data string
+		want error
+	}{
+		{`{"level":"verbose"}`, structlog.ErrUnknownLevel},
+		{`{"unit_levels":{"db":"verbose"}}`, structlog.ErrUnknownLevel},
+		{`{"format":"xml"}`, structlog.ErrUnknownFormat},
+		{`{"color":"sometimes"}`, structlog.ErrUnknownColor},
+	}
+	for _, tc := range tests {
+		t.Run("", func(tt *testing.T) {
+			t := check.T(tt)
+			var cfg structlog.Config
+			t.Err(json.Unmarshal([]byte(tc.data), &cfg), tc.want)
+		})
+	}
+
+	var cfg structlog.Config
+	t.Match(json.Unmarshal([]byte(`{"lvl":"inf"}`), &cfg), `unknown field "lvl"`)
+	log := structlog.New().SetLogLevel(structlog.WRN)
+	level, format := structlog.INF, structlog.Format(42)
+	t.Err((&structlog.Config{Level: &level, Format: &format}).Apply(log), structlog.ErrUnknownFormat)
+	t.False(log.IsInfo(), "not changed")
+
+	log.Info("used")
+	t.Err((&structlog.Config{Level: &level, SuffixKeys: []string{}}).Apply(log), structlog.ErrTooLate)
+	t.False(log.IsInfo(), "not changed")
+	t.Nil((&structlog.Config{Level: &level}).Apply(log))
+	t.True(log.IsInfo())
+}
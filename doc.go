@@ -23,7 +23,7 @@
 // It was designed to produce easy to read, vertically-aligned log lines
 // while your project is small, and later, when your project will grow to
 // use something like ELK Stack, switch by changing one option to
-// produce easy to parse JSON log records.
+// produce easy to parse JSON (or logfmt) log records.
 //
 // You can log key/value pairs without bothering about output format
 // (plain text or JSON) and then fine-tune it plain text output by:
@@ -39,7 +39,13 @@
 //   - caller's file and line
 //   - multiline stack trace (a-la panic output)
 //
-// Supported log levels: Err, Warn, Info and Debug.
+// Supported log levels: Crit, Err, Warn, Info, Debug and Trace. Custom
+// levels may be added using RegisterLevel.
+//
+// Keyvals may also contain [slog.Attr] in place of key/value pair and
+// values of type [slog.Value] or [slog.LogValuer], which will be resolved
+// only if log record is going to be output. Groups are flattened using
+// "group.key" key names.
 //
 // On import it calls stdlib's [log.SetFlags](0) and by default will use
 // stdlib's [log.Print] to output log lines - this is to make sure
@@ -86,11 +92,14 @@
 //
 // ★ Normal logging:
 //
+//	Trace
 //	Debug
 //	Info
 //	Warn
 //	Err
+//	Crit
 //	PrintErr        - like Err, but don't return error (usually you won't need this)
+//	Log             - log with any level (useful for custom levels)
 //
 // ★ Logging useful with defer:
 //
@@ -110,11 +119,21 @@
 //	PrependSuffixKeys
 //	SetKeyValFormat
 //	SetKeysFormat
+//	ParseFormat
 //	SetLogFormat
 //	SetPrefixKeys
 //	SetSuffixKeys
 //	SetTimeFormat
 //	SetTimeValFormat
+//	SetTypedJSON
+//	SetColor        - colorize Text output (by default only on terminal)
+//	SetMessageWidth - pad message to vertically align keys in Text output
+//	SetSanitize     - escape newlines and control chars in Text output
+//	NewSyslogPrinter - use with SetPrinter to send records to syslog
+//	NewJournaldPrinter - use with SetPrinter to send records to systemd-journald
+//	SetFromEnv      - configure using STRUCTLOG_* environment variables
+//	Config.Apply    - configure using Config (e.g. loaded from JSON config file)
+//	Snapshot        - get effective Config of any logger
 //
 // ★ Configuring current logger:
 //
@@ -123,10 +142,36 @@
 //
 // ★ Handling log levels:
 //
+//	IsTrace
 //	IsDebug
 //	IsInfo
+//	LevelVar
+//	NewLevelVar
 //	ParseLevel
+//	ParseLevelStrict
+//	RegisterLevel
+//	SetLevelVar
 //	SetLogLevel
+//	SetUnitLevel
+//	SetUnitLevelVar
+//	UnitLevelVar
+//
+// Level and Format types implement [flag.Value], [encoding.TextUnmarshaler]
+// and [json.Unmarshaler], so they can be used with [flag.Var] and in
+// config structs.
+//
+// Per-unit levels set on a root logger apply to all loggers inherited
+// from it, so it's possible to enable debug output for some packages
+// only:
+//
+//	structlog.DefaultLogger.SetLogLevel(structlog.INF).
+//		SetUnitLevel("db", structlog.DBG).
+//		SetUnitLevel("http", structlog.WRN)
+//
+// Package [github.com/powerman/structlog/levelhandler] provides HTTP
+// handler to view and change LevelVar at runtime, use UnitLevelVar to
+// register per-unit levels with it or NewForLogger to change level of any
+// unit.
 //
 // ★ Passing this logger to 3rd-party packages which expects interface of stdlib's [log.Logger]:
 //
@@ -140,10 +185,30 @@
 //	Printf
 //	Println
 //
+// ★ Using this logger as a backend for [log/slog]:
+//
+//	NewSlogHandler
+//	NewSlogLogger
+//
 // ★ Redirecting log output (useful to redirect to ioutil.Discard in tests):
 //
 //	SetOutput
 //	SetPrinter
+//	SetSink         - output unformatted Record instead of log line
+//	SetOutputs      - output to several outputs, each with own format and level
+//	NewRotatingFile - use with SetOutput to write to file rotated by size/time
+//	NewAsyncPrinter - use with SetPrinter to output in background
+//	NewAsyncWriter  - use with SetPrinter to output in background
+//	Flush           - wait until records buffered by outputs will be written
+//	Formatter       - format Record in the same way as logger does
+//	PrinterSink     - make Sink from Printer and Formatter
+//
+// Printers which also implement Sink (like SyslogPrinter) will receive
+// unformatted Record instead of log line.
+//
+// ★ Reading log output:
+//
+//	ParseText       - parse Text log line back into Record
 //
 //nolint:godox // Allow "Debug".
 package structlog
@@ -0,0 +1,154 @@
+package structlog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables used by SetFromEnv.
+const (
+	EnvLevel      = "STRUCTLOG_LEVEL"       // Log level name, see ParseLevel.
+	EnvUnitLevels = "STRUCTLOG_UNIT_LEVELS" // Per-unit levels: "db=DBG,http=WRN".
+	EnvFormat     = "STRUCTLOG_FORMAT"      // One of: text, json, logfmt.
+	EnvTimeFormat = "STRUCTLOG_TIME_FORMAT" // Time layout, see SetTimeFormat.
+	EnvColor      = "STRUCTLOG_COLOR"       // One of: auto, never, always.
+	EnvSanitize   = "STRUCTLOG_SANITIZE"    // Boolean, see SetSanitize.
+	EnvPrefixKeys = "STRUCTLOG_PREFIX_KEYS" // Comma-separated keys: "_t,_l,_u".
+	EnvSuffixKeys = "STRUCTLOG_SUFFIX_KEYS" // Comma-separated keys: "_f,_s,__".
+)
+
+// ErrBadUnitLevels is returned by SetFromEnv if EnvUnitLevels has invalid
+// syntax.
+var ErrBadUnitLevels = errors.New("bad unit levels, want unit=level,...")
+
+// SetFromEnv configures l using environment variables:
+//
+//	EnvLevel      - SetLogLevel
+//	EnvUnitLevels - SetUnitLevel for each unit
+//	EnvFormat     - SetLogFormat
+//	EnvTimeFormat - SetTimeFormat
+//	EnvColor      - SetColor
+//	EnvSanitize   - SetSanitize
+//	EnvPrefixKeys - SetPrefixKeys
+//	EnvSuffixKeys - SetSuffixKeys
+//
+// Variables which are not set or empty are ignored. Like SetPrefixKeys
+// it must be called before l is used, usually it's called on
+// DefaultLogger at start of main():
+//
+//	if err := structlog.DefaultLogger.SetFromEnv(); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// If any variable has invalid value then l won't be changed and returned
+// error will be ErrUnknownLevel, ErrUnknownFormat, ErrUnknownColor,
+// ErrBadUnitLevels or [strconv.ErrSyntax] wrapped using WrapErr with
+// keyvals "env" (variable name) and "value".
+func (l *Logger) SetFromEnv() error {
+	var (
+		level      Level
+		unitLevels = make(map[string]Level)
+		format     Format
+		color      Color
+		sanitize   bool
+		err        error
+	)
+	s, hasLevel := lookupEnv(EnvLevel)
+	if hasLevel {
+		if level, err = ParseLevelStrict(s); err != nil {
+			return l.envErr(EnvLevel, s, err)
+		}
+	}
+	if s, ok := lookupEnv(EnvUnitLevels); ok {
+		if unitLevels, err = parseUnitLevels(s); err != nil {
+			return l.envErr(EnvUnitLevels, s, err)
+		}
+	}
+	s, hasFormat := lookupEnv(EnvFormat)
+	if hasFormat {
+		if format, err = ParseFormat(s); err != nil {
+			return l.envErr(EnvFormat, s, err)
+		}
+	}
+	s, hasColor := lookupEnv(EnvColor)
+	if hasColor {
+		if color, err = ParseColor(s); err != nil {
+			return l.envErr(EnvColor, s, err)
+		}
+	}
+	s, hasSanitize := lookupEnv(EnvSanitize)
+	if hasSanitize {
+		if sanitize, err = strconv.ParseBool(s); err != nil {
+			return l.envErr(EnvSanitize, s, err)
+		}
+	}
+
+	if hasLevel {
+		l.SetLogLevel(level)
+	}
+	for unit, level := range unitLevels {
+		l.SetUnitLevel(unit, level)
+	}
+	if hasFormat {
+		l.SetLogFormat(format)
+	}
+	if s, ok := lookupEnv(EnvTimeFormat); ok {
+		l.SetTimeFormat(s)
+	}
+	if hasColor {
+		l.SetColor(color)
+	}
+	if hasSanitize {
+		l.SetSanitize(sanitize)
+	}
+	if s, ok := lookupEnv(EnvPrefixKeys); ok {
+		l.SetPrefixKeys(splitList(s)...)
+	}
+	if s, ok := lookupEnv(EnvSuffixKeys); ok {
+		l.SetSuffixKeys(splitList(s)...)
+	}
+	return nil
+}
+
+func (l *Logger) envErr(name, value string, err error) error {
+	return l.WrapErr(fmt.Errorf("%s: %w", name, err), "env", name, "value", value)
+}
+
+// lookupEnv returns value of non-empty environment variable.
+func lookupEnv(name string) (string, bool) {
+	s := strings.TrimSpace(os.Getenv(name))
+	return s, s != ""
+}
+
+// splitList returns comma-separated items of s without spaces and empty
+// items.
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseUnitLevels parses "unit=level,..." into map.
+func parseUnitLevels(s string) (map[string]Level, error) {
+	unitLevels := make(map[string]Level)
+	for _, item := range splitList(s) {
+		unit, levelName, ok := strings.Cut(item, "=")
+		unit = strings.TrimSpace(unit)
+		if !ok || unit == "" {
+			return nil, ErrBadUnitLevels
+		}
+		level, err := ParseLevelStrict(strings.TrimSpace(levelName))
+		if err != nil {
+			return nil, err
+		}
+		unitLevels[unit] = level
+	}
+	return unitLevels, nil
+}
@@ -0,0 +1,73 @@
+package structlog_test
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestSetFromEnv(tt *testing.T) {
+	t := check.T(tt)
+	var buf bytes.Buffer
+	log := structlog.NewZeroLogger().SetOutput(&buf)
+
+	t.Nil(structlog.New().SetFromEnv())
+
+	t.Setenv(structlog.EnvLevel, "warn")
+	t.Setenv(structlog.EnvUnitLevels, " db=DBG, ,"+unit+"=inf ")
+	t.Setenv(structlog.EnvFormat, "JSON")
+	t.Setenv(structlog.EnvTimeFormat, "15:04")
+	t.Setenv(structlog.EnvPrefixKeys, "_l, _u")
+	t.Setenv(structlog.EnvSuffixKeys, "_s")
+	t.Setenv(structlog.EnvColor, "always")
+	t.Setenv(structlog.EnvSanitize, "1")
+	t.Nil(log.SetFromEnv())
+	log.Debug("skip")
+	log.Info("shown")
+	log.Info("skip", structlog.KeyUnit, "other")
+	log.Debug("shown", structlog.KeyUnit, "db")
+	t.Equal(buf.String(), ""+
+		`{"_t":"02:04","_l":"inf","_u":"`+unit+`","_m":"shown","_s":"env_test.go:30"}`+"\n"+
+		`{"_t":"02:04","_l":"dbg","_u":"db","_m":"shown","_s":"env_test.go:32"}`+"\n")
+	t.Equal(*log.Snapshot().Color, structlog.ColorAlways)
+	t.True(*log.Snapshot().Sanitize)
+}
+
+func TestSetFromEnvErrors(tt *testing.T) {
+	t := check.T(tt)
+	var buf bytes.Buffer
+	log := structlog.New().SetOutput(&buf).SetLogLevel(structlog.INF)
+
+	tests := []struct {
+		env   string
+		value string
+		want  error
+	}{
+		{structlog.EnvLevel, "verbose", structlog.ErrUnknownLevel},
+		{structlog.EnvUnitLevels, "db=DBG,http", structlog.ErrBadUnitLevels},
+		{structlog.EnvUnitLevels, "=DBG", structlog.ErrBadUnitLevels},
+		{structlog.EnvUnitLevels, "db=verbose", structlog.ErrUnknownLevel},
+		{structlog.EnvFormat, "xml", structlog.ErrUnknownFormat},
+		{structlog.EnvColor, "sometimes", structlog.ErrUnknownColor},
+		{structlog.EnvSanitize, "maybe", strconv.ErrSyntax},
+	}
+	for _, tc := range tests {
+		t.Run("", func(tt *testing.T) {
+			t := check.T(tt)
+			t.Setenv(structlog.EnvLevel, "ERR")
+			t.Setenv(tc.env, tc.value)
+			err := log.SetFromEnv()
+			t.Err(err, tc.want)
+			t.Match(err, "^"+tc.env+": ")
+			t.True(log.IsInfo(), "not changed")
+		})
+	}
+
+	t.Setenv(structlog.EnvFormat, "yaml")
+	log.PrintErr(log.SetFromEnv())
+	t.Match(buf.String(), " `STRUCTLOG_FORMAT: unknown log format` env=STRUCTLOG_FORMAT value=yaml ")
+}
@@ -0,0 +1,254 @@
+package structlog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// keyFormat is a key/value format string (see SetKeysFormat) compiled
+// to output key/value pairs without using fmt in most cases.
+type keyFormat struct {
+	format string
+	parts  []formatPart // Nil if format is too complex and needs fmt.
+}
+
+// formatPart is either a literal text or a single fmt verb.
+type formatPart struct {
+	lit   string
+	arg   int  // 0 for literal text, 1 for key, 2 for value.
+	verb  byte // One of: s v d q.
+	sharp bool
+	fmt   string // Verb as fmt format string, used for fallback.
+}
+
+// verbV is used to output values in the manner of [fmt.Sprint].
+var verbV = formatPart{arg: 2, verb: 'v', fmt: "%v"} //nolint:gochecknoglobals // Const.
+
+// compileFormat returns format compiled to parts if format contains only
+// simple verbs (%s, %v, %d, %q, %#q with optional [1] or [2] argument
+// index) and result of fmt.Sprintf(format, key, value) won't include
+// errors about missing or extra arguments.
+func compileFormat(format string) *keyFormat { //nolint:gocognit // No idea how to simplify.
+	kf := &keyFormat{format: format}
+	parts := make([]formatPart, 0, 4) //nolint:mnd // Typical: literal, key, literal, value.
+	lit := make([]byte, 0, len(format))
+	argNum, reordered := 0, false
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			lit = append(lit, format[i])
+			continue
+		}
+		if i++; i < len(format) && format[i] == '%' {
+			lit = append(lit, '%')
+			continue
+		}
+		part := formatPart{arg: argNum + 1}
+		if i < len(format) && format[i] == '#' {
+			part.sharp = true
+			i++
+		}
+		const indexLen = 3 // Like "[1]".
+		if i+indexLen <= len(format) && format[i] == '[' && format[i+2] == ']' {
+			part.arg = int(format[i+1] - '0')
+			reordered = true
+			i += indexLen
+		}
+		if i >= len(format) || part.arg < 1 || part.arg > 2 {
+			return kf
+		}
+		switch part.verb = format[i]; part.verb {
+		case 's', 'v', 'd':
+			if part.sharp {
+				return kf
+			}
+			part.fmt = "%" + string(part.verb)
+		case 'q':
+			part.fmt = "%q"
+			if part.sharp {
+				part.fmt = "%#q"
+			}
+		default:
+			return kf
+		}
+		if len(lit) > 0 {
+			parts = append(parts, formatPart{lit: string(lit)})
+			lit = lit[:0]
+		}
+		parts = append(parts, part)
+		argNum = part.arg
+	}
+	if !reordered && argNum != 2 {
+		return kf
+	}
+	if len(lit) > 0 {
+		parts = append(parts, formatPart{lit: string(lit)})
+	}
+	kf.parts = parts
+	return kf
+}
+
+// appendKeyVal appends key and val formatted according to kf.
+func (kf *keyFormat) appendKeyVal(buf []byte, key string, val any) []byte {
+	if kf.parts == nil {
+		return fmt.Appendf(buf, kf.format, key, val)
+	}
+	for i := range kf.parts {
+		p := &kf.parts[i]
+		switch p.arg {
+		case 0:
+			buf = append(buf, p.lit...)
+		case 1:
+			buf = p.appendString(buf, key)
+		default:
+			buf = p.appendArg(buf, val)
+		}
+	}
+	return buf
+}
+
+// appendArg appends v formatted according to p.
+// It's a faster version of fmt.Appendf(buf, p.fmt, v) for common types.
+func (p *formatPart) appendArg(buf []byte, v any) []byte { //nolint:gocyclo,cyclop // Type switch.
+	intVerb := p.verb == 'v' || p.verb == 'd'
+	switch v := v.(type) {
+	case string:
+		return p.appendString(buf, v)
+	case *strRef:
+		if p.verb != 'd' {
+			return p.appendString(buf, v.s)
+		}
+	case Level:
+		if p.verb == 's' || p.verb == 'v' {
+			return append(buf, v.String()...)
+		}
+	case *sourceRef:
+		if p.verb == 's' || p.verb == 'v' {
+			return v.appendTo(buf)
+		}
+	case *timeRef:
+		if p.verb == 's' || p.verb == 'v' {
+			return v.appendTo(buf)
+		}
+	case int:
+		if intVerb {
+			return strconv.AppendInt(buf, int64(v), 10)
+		}
+	case int64:
+		if intVerb {
+			return strconv.AppendInt(buf, v, 10)
+		}
+	case int32:
+		if intVerb {
+			return strconv.AppendInt(buf, int64(v), 10)
+		}
+	case uint:
+		if intVerb {
+			return strconv.AppendUint(buf, uint64(v), 10)
+		}
+	case uint64:
+		if intVerb {
+			return strconv.AppendUint(buf, v, 10)
+		}
+	case uint32:
+		if intVerb {
+			return strconv.AppendUint(buf, uint64(v), 10)
+		}
+	case bool:
+		if p.verb == 'v' {
+			return strconv.AppendBool(buf, v)
+		}
+	}
+	return fmt.Appendf(buf, p.fmt, v)
+}
+
+// appendString appends s formatted according to p.
+func (p *formatPart) appendString(buf []byte, s string) []byte {
+	switch p.verb {
+	case 's', 'v':
+		return append(buf, s...)
+	case 'q':
+		if p.sharp && strconv.CanBackquote(s) {
+			buf = append(buf, '`')
+			buf = append(buf, s...)
+			return append(buf, '`')
+		}
+		return strconv.AppendQuote(buf, s)
+	default:
+		return fmt.Appendf(buf, p.fmt, s)
+	}
+}
+
+// appendSanitized replaces buf[start:] with escaped version if it
+// contains control characters, line separators, backslashes or invalid
+// UTF-8. Tabs are kept as is if keepTab is true.
+func appendSanitized(buf []byte, start int, keepTab bool) []byte {
+	if !needSanitize(buf[start:], keepTab) {
+		return buf
+	}
+	s := string(buf[start:])
+	buf = buf[:start]
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case !isUnsafeRune(r, size, keepTab):
+			buf = append(buf, s[i:i+size]...)
+		case r == '\\':
+			buf = append(buf, `\\`...)
+		case r == '\n':
+			buf = append(buf, `\n`...)
+		case r == '\r':
+			buf = append(buf, `\r`...)
+		case r == '\t':
+			buf = append(buf, `\t`...)
+		case r < utf8.RuneSelf || size == 1: // Also invalid UTF-8.
+			buf = append(buf, '\\', 'x', hex[s[i]>>4], hex[s[i]&0xF])
+		default:
+			buf = append(buf, '\\', 'u', hex[r>>12&0xF], hex[r>>8&0xF], hex[r>>4&0xF], hex[r&0xF])
+		}
+		i += size
+	}
+	return buf
+}
+
+// appendSanitizedQuoted is like appendSanitized (without keeping tabs)
+// for buf[start:] output using %q or %#q. Output of %q is already
+// escaped, except back-quoted string, which will be double-quoted if it
+// needs escaping.
+func appendSanitizedQuoted(buf []byte, start int) []byte {
+	b := buf[start:]
+	if len(b) < 2 || b[0] != '`' || b[len(b)-1] != '`' || bytes.IndexByte(b[1:len(b)-1], '`') != -1 {
+		return buf
+	}
+	s := b[1 : len(b)-1]
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRune(s[i:])
+		if r != '\\' && isUnsafeRune(r, size, false) { // Backslash is safe inside back quotes.
+			return strconv.AppendQuote(buf[:start], string(s))
+		}
+		i += size
+	}
+	return buf
+}
+
+func needSanitize(b []byte, keepTab bool) bool {
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if isUnsafeRune(r, size, keepTab) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// isUnsafeRune returns true for control characters, line separators,
+// backslash and invalid UTF-8 (r is utf8.RuneError and size is 1).
+func isUnsafeRune(r rune, size int, keepTab bool) bool {
+	return r == '\t' && !keepTab ||
+		r != '\t' && unicode.IsControl(r) ||
+		r == '\u2028' || r == '\u2029' || r == '\\' ||
+		r == utf8.RuneError && size == 1
+}
@@ -0,0 +1,85 @@
+//nolint:testpackage // Testing unexported.
+package structlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/powerman/check"
+)
+
+func TestCompileFormat(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	formats := []string{
+		DefaultKeyValFormat,
+		"%[2]s ",
+		"[%[2]d]",
+		" %#[2]q",
+		" %[2]q",
+		" \t@ %[2]s",
+		"%%%[1]s:%%%[2]v%%",
+		"%s",
+		"%s=%v=%v",
+		"%[1]s=%v",
+		"%[2]s=%[1]v",
+		"%-10[2]v",
+		"%[3]v",
+		"%[2]x",
+		"%#[2]v",
+		"%[2]",
+		"%",
+		"",
+	}
+	vals := []any{
+		nil, "text", "with `quote`", "line\nbreak", 42, int64(-42), int32(42), uint(42), uint64(42), uint32(42),
+		true, 3.14, INF, errors.New("oops"), []int{1, 2}, //nolint:err113 // Test.
+		&strRef{s: "ref"}, &sourceRef{file: "file.go", line: 42},
+	}
+	for _, format := range formats {
+		kf := compileFormat(format)
+		for _, v := range vals {
+			t.Equal(string(kf.appendKeyVal(nil, "key", v)), fmt.Sprintf(format, "key", v), format, v)
+		}
+	}
+	t.NotNil(compileFormat(DefaultKeyValFormat).parts)
+	t.NotNil(compileFormat(" %#[2]q").parts)
+	t.Nil(compileFormat("%s").parts)
+	t.Nil(compileFormat("%-10[2]v").parts)
+}
+
+func TestAppendJSONString(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	for _, s := range []string{"", "text", `"quote" \ <html> & co`, "\x00\b\f\n\r\t\x1f\x7f", "ü  ", "bad\xffutf8"} {
+		want, err := json.Marshal(s)
+		t.Nil(err)
+		t.Equal(string(appendJSONString(nil, s)), string(want), s)
+		t.Equal(string(appendJSONString(nil, []byte(s))), string(want), s)
+	}
+}
+
+func TestAppendSanitized(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	tests := []struct {
+		s       string
+		keepTab bool
+		want    string
+	}{
+		{"", false, ""},
+		{"plain ü `text`", false, "plain ü `text`"},
+		{"a\nb\r\nc\td", false, `a\nb\r\nc\td`},
+		{"a\nb\r\nc\td", true, "a\\nb\\r\\nc\td"},
+		{"\x00\x1b[31mred\x7f", false, `\x00\x1b[31mred\x7f`},
+		{"\u0085 \u2028 \u2029", false, `\u0085 \u2028 \u2029`},
+		{"bad\xff\nutf8\x9b", false, `bad\xff\nutf8\x9b`},
+		{`back\slash \n`, false, `back\\slash \\n`},
+	}
+	for _, tc := range tests {
+		buf := appendSanitized([]byte("prefix\n"+tc.s), len("prefix\n"), tc.keepTab)
+		t.Equal(string(buf), "prefix\n"+tc.want, tc.s)
+	}
+}
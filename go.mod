@@ -2,7 +2,10 @@ module github.com/powerman/structlog
 
 go 1.25.0
 
-require github.com/powerman/check v1.9.1
+require (
+	github.com/powerman/check v1.9.1
+	golang.org/x/sys v0.42.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -10,7 +13,6 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/powerman/deepequal v0.1.0 // indirect
 	github.com/smartystreets/goconvey v1.8.1 // indirect
-	golang.org/x/sys v0.42.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20260406210006-6f92a3bedf2d // indirect
 	google.golang.org/grpc v1.80.0 // indirect
 	google.golang.org/protobuf v1.36.11 // indirect
@@ -0,0 +1,118 @@
+// Package jsonlog parses log records output by structlog in JSON format.
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/powerman/structlog"
+)
+
+// ErrNotObject is returned by Parse if line is not a JSON object.
+var ErrNotObject = errors.New("not a JSON object")
+
+// Parse returns Record for JSON log line, with Keyvals in same order as
+// in line. KeyTime value is parsed using timeFormat (time.RFC3339Nano is
+// also accepted), in UTC, and converted to local time. Year will be set
+// to current year if timeFormat has no year (like structlog's default).
+//
+// Strings, bools and numbers are converted to Go types (KeyPID to int
+// even if it's a string), other values are kept as strings with JSON.
+// Record.Level is INF if KeyLevel is missing or unknown.
+func Parse(line []byte, timeFormat string) (*structlog.Record, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, ErrNotObject
+	}
+	rec := &structlog.Record{Level: structlog.INF}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		k, _ := tok.(string)
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		v := value(raw)
+		switch k {
+		case structlog.KeyTime:
+			if t, ok := parseTime(v, timeFormat); ok {
+				rec.Time, v = t, t
+			}
+		case structlog.KeyLevel:
+			if s, ok := v.(string); ok {
+				if level, err := structlog.ParseLevelStrict(s); err == nil {
+					rec.Level, v = level, level
+				}
+			}
+		case structlog.KeyPID:
+			if s, ok := v.(string); ok {
+				if pid, err := strconv.Atoi(s); err == nil {
+					v = pid
+				}
+			}
+		case structlog.KeyMessage:
+			rec.Message = v
+		case structlog.KeyUnit:
+			rec.Unit = fmt.Sprint(v)
+		case structlog.KeyFunc:
+			rec.Func = fmt.Sprint(v)
+		case structlog.KeySource:
+			rec.Source = fmt.Sprint(v)
+		case structlog.KeyStack:
+			rec.Stack = fmt.Sprint(v)
+		}
+		rec.Keyvals = append(rec.Keyvals, k, v)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// value converts JSON value to Go type.
+func value(raw json.RawMessage) any {
+	switch raw[0] {
+	case '"':
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return s
+		}
+	case 'n':
+		return nil
+	case 't', 'f':
+		return raw[0] == 't'
+	case '{', '[':
+	default:
+		if i, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(string(raw), 64); err == nil {
+			return f
+		}
+	}
+	return string(raw)
+}
+
+func parseTime(v any, timeFormat string) (time.Time, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(timeFormat, s, time.UTC)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return time.Time{}, false
+		}
+	}
+	if t.Year() == 0 {
+		t = t.AddDate(time.Now().UTC().Year(), 0, 0)
+	}
+	return t.Local(), true
+}
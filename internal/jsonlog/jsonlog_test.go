@@ -0,0 +1,57 @@
+package jsonlog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+	"github.com/powerman/structlog/internal/jsonlog"
+)
+
+func TestMain(m *testing.M) { check.TestMain(m) }
+
+func TestParse(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	rec, err := jsonlog.Parse([]byte(`{"_t":"2020-01-02T03:04:05.123Z","_a":"app","_p":"42","_l":"WRN","_u":"db",`+
+		`"_m":"msg","n":1,"f":1.5,"b":true,"nil":null,"obj":{"a": [1]},"_f":"main.f","_s":"main.go:42"}`), time.RFC3339)
+	t.Nil(err)
+	wantTime := time.Date(2020, 1, 2, 3, 4, 5, 123000000, time.UTC).Local()
+	t.DeepEqual(rec, &structlog.Record{
+		Time:    wantTime,
+		Level:   structlog.WRN,
+		Message: "msg",
+		Unit:    "db",
+		Func:    "main.f",
+		Source:  "main.go:42",
+		Keyvals: []any{
+			structlog.KeyTime, wantTime,
+			structlog.KeyApp, "app",
+			structlog.KeyPID, 42,
+			structlog.KeyLevel, structlog.WRN,
+			structlog.KeyUnit, "db",
+			structlog.KeyMessage, "msg",
+			"n", int64(1),
+			"f", 1.5,
+			"b", true,
+			"nil", nil,
+			"obj", `{"a": [1]}`,
+			structlog.KeyFunc, "main.f",
+			structlog.KeySource, "main.go:42",
+		},
+	})
+
+	rec, err = jsonlog.Parse([]byte(`{"_t":"Jan  2 03:04:05.123456","_l":"custom"}`), structlog.DefaultTimeFormat)
+	t.Nil(err)
+	t.Equal(rec.Time.UTC().Year(), time.Now().UTC().Year())
+	t.Equal(rec.Time.UTC().Format(structlog.DefaultTimeFormat), "Jan  2 03:04:05.123456")
+	t.Equal(rec.Level, structlog.INF)
+	t.DeepEqual(rec.Keyvals[2:], []any{structlog.KeyLevel, "custom"})
+
+	for _, line := range []string{``, `text`, `[1]`, `{"a":1`, `{"a":}`} {
+		_, err = jsonlog.Parse([]byte(line), structlog.DefaultTimeFormat)
+		t.NotNil(err, line)
+	}
+}
@@ -0,0 +1,212 @@
+package structlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+)
+
+// DefaultJournaldAddr is a path to journald's native protocol socket.
+const DefaultJournaldAddr = "/run/systemd/journal/socket"
+
+const journaldMaxFieldName = 64
+
+// JournaldPrinter is a Printer which sends log records to systemd-journald
+// using native protocol.
+//
+// When used by Logger it sends each log record using these fields:
+//
+//   - MESSAGE: value of KeyMessage
+//   - PRIORITY: syslog severity calculated from log level
+//   - SYSLOG_IDENTIFIER: value of KeyApp (or current app name)
+//   - SYSLOG_PID: value of KeyPID
+//   - CODE_FILE and CODE_LINE: value of KeySource
+//   - CODE_FUNC: value of KeyFunc
+//   - CODE_UNIT: value of KeyUnit (this is not a journald's well-known
+//     field, it's added by structlog to match CODE_FUNC)
+//   - STACK_TRACE: value of KeyStack
+//   - other keys which would be output by Logger, except KeyTime and
+//     KeyLevel, converted to valid field names: uppercased, chars other
+//     than A-Z, 0-9 and '_' replaced by '_', leading '_' removed, "F_"
+//     prepended if result doesn't start with a letter
+//
+// Logger's format settings (SetLogFormat, SetKeysFormat, etc.) are not
+// used by JournaldPrinter.
+//
+// Entries too large to be sent as a single datagram (e.g. with long
+// stack trace) are passed to journald using memfd (on Linux only).
+type JournaldPrinter struct {
+	mu       sync.Mutex
+	addr     string
+	fallback Printer
+	conn     *net.UnixConn
+}
+
+// NewJournaldPrinter returns a Printer which sends log records to
+// journald's native protocol socket at addr (DefaultJournaldAddr if
+// empty).
+//
+// Socket will be opened on first use and reopened after errors.
+func NewJournaldPrinter(addr string) *JournaldPrinter {
+	if addr == "" {
+		addr = DefaultJournaldAddr
+	}
+	return &JournaldPrinter{
+		addr:     addr,
+		fallback: PrinterFunc(log.Print),
+	}
+}
+
+// SetFallback changes Printer used to output messages which failed to be
+// sent to journald together with error (default value is
+// PrinterFunc(log.Print)).
+//
+// It returns p just for convenience.
+func (p *JournaldPrinter) SetFallback(fallback Printer) *JournaldPrinter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fallback = fallback
+	return p
+}
+
+// Print implements Printer. It sends v formatted in the manner of
+// [fmt.Print] as a message with INF level.
+func (p *JournaldPrinter) Print(v ...any) { p.Handle(printRecord(v...)) }
+
+// Handle implements Sink.
+func (p *JournaldPrinter) Handle(r *Record) {
+	app, _ := r.Get(KeyApp)
+	msg, _ := r.Get(KeyMessage)
+	e := &journaldEntry{}
+	e.appendHeader(r.Level, app, msg)
+	for i := 0; i+1 < len(r.Keyvals); i += 2 {
+		k, _ := r.Keyvals[i].(string)
+		e.appendKeyval(k, r.Keyvals[i+1])
+	}
+	p.send(e.buf)
+}
+
+// Close closes journald socket. It will be reopened on next Print.
+func (p *JournaldPrinter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closeConn()
+}
+
+// journaldEntry is used to build journald native protocol entry.
+type journaldEntry struct {
+	buf     []byte
+	scratch []byte
+}
+
+func (e *journaldEntry) appendHeader(level Level, app, msg any) {
+	if app == nil {
+		app = getAppName()
+	}
+	e.appendField("MESSAGE", msg)
+	e.appendField("PRIORITY", syslogSeverity(level))
+	e.appendField("SYSLOG_IDENTIFIER", app)
+}
+
+func (e *journaldEntry) appendKeyval(k string, v any) {
+	switch k {
+	case KeyTime, KeyLevel, KeyApp, KeyMessage:
+	case KeyPID:
+		e.appendField("SYSLOG_PID", v)
+	case KeyFunc:
+		e.appendField("CODE_FUNC", v)
+	case KeyUnit:
+		e.appendField("CODE_UNIT", v)
+	case KeyStack:
+		e.appendField("STACK_TRACE", v)
+	case KeySource:
+		src, _ := v.(string)
+		if i := strings.LastIndexByte(src, ':'); i > 0 {
+			e.appendField("CODE_FILE", src[:i])
+			e.appendField("CODE_LINE", src[i+1:])
+		} else {
+			e.appendField("CODE_FILE", v)
+		}
+	default:
+		e.appendField(journaldFieldName(k), v)
+	}
+}
+
+// appendField appends field using binary format if v formatted in the
+// manner of fmt.Sprint contains newline.
+func (e *journaldEntry) appendField(name string, v any) {
+	e.scratch = verbV.appendArg(e.scratch[:0], v)
+	e.buf = append(e.buf, name...)
+	if strings.IndexByte(string(e.scratch), '\n') == -1 {
+		e.buf = append(e.buf, '=')
+	} else {
+		e.buf = append(e.buf, '\n')
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(len(e.scratch)))
+	}
+	e.buf = append(e.buf, e.scratch...)
+	e.buf = append(e.buf, '\n')
+}
+
+// journaldFieldName converts k to valid journald field name.
+func journaldFieldName(k string) string {
+	name := make([]byte, 0, len(k)+len("F_"))
+	for _, b := range []byte(strings.TrimLeft(k, "_")) {
+		switch {
+		case 'a' <= b && b <= 'z':
+			b -= 'a' - 'A'
+		case 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		default:
+			b = '_'
+		}
+		name = append(name, b)
+	}
+	if len(name) == 0 || name[0] < 'A' || name[0] > 'Z' {
+		name = append([]byte("F_"), name...)
+	}
+	return string(name[:min(len(name), journaldMaxFieldName)])
+}
+
+// send sends entry to journald, reopening socket if needed.
+// In case of error entry will be output using fallback.
+func (p *JournaldPrinter) send(entry []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.write(entry)
+	if err != nil && !isTooLarge(err) {
+		_ = p.closeConn()
+		err = p.write(entry)
+	}
+	if isTooLarge(err) {
+		err = sendJournaldFD(p.conn, entry)
+	}
+	if err != nil {
+		_ = p.closeConn()
+		p.fallback.Print(fmt.Sprintf("journald: %v: %s", err, entry))
+	}
+}
+
+// write must be called with p.mu locked.
+func (p *JournaldPrinter) write(entry []byte) error {
+	if p.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: p.addr, Net: "unixgram"})
+		if err != nil {
+			return err
+		}
+		p.conn = conn
+	}
+	_, err := p.conn.Write(entry)
+	return err
+}
+
+// closeConn must be called with p.mu locked.
+func (p *JournaldPrinter) closeConn() error {
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
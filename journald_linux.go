@@ -0,0 +1,45 @@
+package structlog
+
+import (
+	"errors"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// isTooLarge reports is entry can't be sent as a single datagram.
+func isTooLarge(err error) bool {
+	return errors.Is(err, unix.EMSGSIZE) || errors.Is(err, unix.ENOBUFS)
+}
+
+// sendJournaldFD sends entry to journald using sealed memfd.
+func sendJournaldFD(conn *net.UnixConn, entry []byte) error {
+	fd, err := unix.MemfdCreate("structlog", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return os.NewSyscallError("memfd_create", err)
+	}
+	f := os.NewFile(uintptr(fd), "memfd") //nolint:gosec // File descriptor is not negative.
+	defer f.Close()
+	if _, err = f.Write(entry); err != nil {
+		return err
+	}
+	const seals = unix.F_SEAL_SEAL | unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE
+	if _, err = unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		return os.NewSyscallError("fcntl", err)
+	}
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	// WriteMsgUnix can't be used with connected datagram socket.
+	var sendErr error
+	err = rc.Write(func(connFD uintptr) bool {
+		sendErr = unix.Sendmsg(int(connFD), nil, unix.UnixRights(fd), nil, 0) //nolint:gosec // File descriptor fits int.
+		return !errors.Is(sendErr, unix.EAGAIN)
+	})
+	if err != nil {
+		return err
+	}
+	return os.NewSyscallError("sendmsg", sendErr)
+}
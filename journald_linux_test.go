@@ -0,0 +1,45 @@
+package structlog_test
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestJournaldLarge(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	ln, addr := listenJournald(t)
+
+	p := structlog.NewJournaldPrinter(addr)
+	defer p.Close()
+	stack := strings.Repeat("frame\n", 1<<20)
+	structlog.New().SetPrinter(p).Err("large", structlog.KeyStack, stack)
+
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := ln.ReadMsgUnix(nil, oob)
+	t.Nil(err)
+	t.Zero(n)
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	t.Nil(err)
+	t.Must(t.Len(msgs, 1))
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	t.Nil(err)
+	t.Must(t.Len(fds, 1))
+	f := os.NewFile(uintptr(fds[0]), "memfd")
+	defer f.Close()
+	_, err = f.Write([]byte("x"))
+	t.NotNil(err, "sealed")
+	buf, err := os.ReadFile("/proc/self/fd/" + strconv.Itoa(fds[0]))
+	t.Nil(err)
+	fields := parseJournald(t, buf)
+	t.Equal(fields["MESSAGE"], "large")
+	t.Equal(fields["PRIORITY"], "3")
+	t.Equal(fields["STACK_TRACE"], stack)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package structlog
+
+import (
+	"errors"
+	"net"
+)
+
+// isTooLarge returns false because memfd is supported on Linux only.
+func isTooLarge(error) bool { return false }
+
+// sendJournaldFD returns error because memfd is supported on Linux only.
+func sendJournaldFD(*net.UnixConn, []byte) error {
+	return errors.New("entry is too large")
+}
@@ -0,0 +1,92 @@
+package structlog_test
+
+import (
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+// parseJournald returns fields of journald native protocol entry.
+func parseJournald(t *check.C, entry []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(entry) > 0 {
+		i := strings.IndexAny(string(entry), "=\n")
+		t.Must(t.True(i > 0))
+		name := string(entry[:i])
+		if entry[i] == '=' {
+			entry = entry[i+1:]
+			j := strings.IndexByte(string(entry), '\n')
+			t.Must(t.True(j >= 0))
+			fields[name], entry = string(entry[:j]), entry[j+1:]
+		} else {
+			size := int(binary.LittleEndian.Uint64(entry[i+1:]))
+			entry = entry[i+1+8:]
+			fields[name], entry = string(entry[:size]), entry[size+1:]
+		}
+	}
+	return fields
+}
+
+func listenJournald(t *check.C) (*net.UnixConn, string) {
+	t.Helper()
+	addr := filepath.Join(t.TempDir(), "socket")
+	ln, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	t.Must(t.Nil(err))
+	t.Cleanup(func() { ln.Close() })
+	return ln, addr
+}
+
+func TestJournald(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	ln, addr := listenJournald(t)
+
+	p := structlog.NewJournaldPrinter(addr)
+	defer p.Close()
+	log := structlog.New(structlog.KeyApp, "app").SetPrinter(p)
+	log.Warn("hello", "user-id", 42, "9x", "a\nb", structlog.KeyStack, "trace\n")
+
+	buf := make([]byte, 4096)
+	n, err := ln.Read(buf)
+	t.Nil(err)
+	t.DeepEqual(parseJournald(t, buf[:n]), map[string]string{
+		"MESSAGE":           "hello",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "app",
+		"SYSLOG_PID":        pid,
+		"CODE_UNIT":         unit,
+		"CODE_FUNC":         "structlog_test.TestJournald",
+		"CODE_FILE":         "journald_test.go",
+		"CODE_LINE":         "54",
+		"USER_ID":           "42",
+		"F_9X":              "a\nb",
+		"STACK_TRACE":       "trace\n",
+	})
+
+	p.Print("plain\n")
+	n, err = ln.Read(buf)
+	t.Nil(err)
+	t.DeepEqual(parseJournald(t, buf[:n]), map[string]string{
+		"MESSAGE":           "plain",
+		"PRIORITY":          "6",
+		"SYSLOG_IDENTIFIER": "structlog.test",
+	})
+}
+
+func TestJournaldFallback(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var got []string
+	p := structlog.NewJournaldPrinter(filepath.Join(t.TempDir(), "none")).
+		SetFallback(structlog.PrinterFunc(func(v ...any) { got = append(got, v[0].(string)) }))
+	structlog.New().SetPrinter(p).Info("lost")
+	t.Len(got, 1)
+	t.Match(got[0], `^journald: dial unixgram .*: no such file or directory: MESSAGE=lost\n`)
+}
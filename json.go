@@ -1,16 +1,90 @@
 package structlog
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"time"
+	"unicode/utf8"
 )
 
-type kvs map[string]any
+// marshalTyped returns v as JSON value of v's own type if possible,
+// otherwise it returns v in the manner of fmt.Sprint as JSON string.
+func marshalTyped(v any) []byte {
+	if v == nil {
+		return []byte("null")
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return []byte("null")
+	}
+	switch v := v.(type) {
+	case json.Marshaler, encoding.TextMarshaler, time.Duration:
+		// Has own JSON representation.
+	case error:
+		return appendJSONString(nil, v.Error())
+	case fmt.Stringer:
+		return appendJSONString(nil, v.String())
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(nil, fmt.Sprint(v))
+	}
+	return buf
+}
+
+const hex = "0123456789abcdef"
 
-func (kv kvs) MarshalJSON() ([]byte, error) {
-	safe := make(map[string]string, len(kv))
-	for k, v := range kv {
-		safe[k] = fmt.Sprint(v)
+// appendJSONString appends s as JSON string escaped in same way as
+// [json.Marshal] does.
+func appendJSONString[T string | []byte](buf []byte, s T) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= ' ' && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch b {
+			case '\\', '"':
+				buf = append(buf, '\\', b)
+			case '\b':
+				buf = append(buf, '\\', 'b')
+			case '\f':
+				buf = append(buf, '\\', 'f')
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		var rb [utf8.UTFMax]byte
+		r, size := utf8.DecodeRune(rb[:copy(rb[:], s[i:])])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
 	}
-	return json.Marshal(safe)
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
 }
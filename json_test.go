@@ -0,0 +1,112 @@
+package structlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+type stringer struct{ v int }
+
+func (s stringer) String() string { return "stringer" }
+
+func TestTypedJSON(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := structlog.New().SetOutput(&buf).SetLogFormat(structlog.JSON).SetTypedJSON(true)
+	type V struct {
+		S string
+		F func()
+		I int
+	}
+	log.Info(42,
+		"int", 42,
+		"float", 1.5,
+		"nan", math.NaN(),
+		"bool", true,
+		"nil", nil,
+		"nilptr", (*V)(nil),
+		"str", "text",
+		"dur", 1500*time.Millisecond,
+		"time", time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC),
+		"slice", []int{1, 2},
+		"map", map[string]int{"a": 1},
+		"struct", struct{ A, b int }{A: 1, b: 2},
+		"bad", V{S: "text", I: 42},
+		"ip", net.IPv4(127, 0, 0, 1),
+		"stringer", stringer{v: 1},
+		"err", io.EOF,
+	)
+	m := make(map[string]any)
+	t.Nil(json.Unmarshal(buf.Bytes(), &m))
+	t.DeepEqual(m, map[string]any{
+		"_a":       "structlog.test",
+		"_f":       "structlog_test.TestTypedJSON",
+		"_l":       "inf",
+		"_m":       "42",
+		"_p":       float64(os.Getpid()),
+		"_s":       "json_test.go:32",
+		"_t":       "Jan  2 02:04:05.123456",
+		"_u":       unit,
+		"int":      float64(42),
+		"float":    1.5,
+		"nan":      "NaN",
+		"bool":     true,
+		"nil":      nil,
+		"nilptr":   nil,
+		"str":      "text",
+		"dur":      float64(1500 * time.Millisecond),
+		"time":     "2020-01-02T03:04:05Z",
+		"slice":    []any{float64(1), float64(2)},
+		"map":      map[string]any{"a": float64(1)},
+		"struct":   map[string]any{"A": float64(1)},
+		"bad":      "{text <nil> 42}",
+		"ip":       "127.0.0.1",
+		"stringer": "stringer",
+		"err":      "EOF",
+	})
+
+	buf.Reset()
+	log.New().SetTypedJSON(false).Info("untyped", "int", 42)
+	m = make(map[string]any)
+	t.Nil(json.Unmarshal(buf.Bytes(), &m))
+	t.Equal(m["int"], "42")
+	t.Equal(m["_p"], pid)
+}
+
+func TestJSONKeysOrder(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := structlog.New().SetOutput(&buf).SetLogFormat(structlog.JSON)
+	log.Info("msg", "z", 1, "a", 2, structlog.KeyStack, "stack", "z", 3)
+	t.Equal(buf.String(), `{`+
+		`"_t":"Jan  2 02:04:05.123456",`+
+		`"_a":"structlog.test",`+
+		`"_p":"`+pid+`",`+
+		`"_l":"inf",`+
+		`"_u":"`+unit+`",`+
+		`"_m":"msg",`+
+		`"z":"3",`+
+		`"a":"2",`+
+		`"_f":"structlog_test.TestJSONKeysOrder",`+
+		`"_s":"json_test.go:92",`+
+		`"__":"stack"`+
+		"}\n")
+
+	buf.Reset()
+	structlog.NewZeroLogger().SetOutput(&buf).SetLogFormat(structlog.JSON).
+		SetPrefixKeys(structlog.KeyApp).SetSuffixKeys(structlog.KeyApp).
+		Warn("msg", "b", true, structlog.KeyApp, "app")
+	t.Equal(buf.String(), `{"_t":"Jan  2 02:04:05.123456","_a":"app","_l":"WRN","_m":"msg","b":"true"}`+"\n")
+}
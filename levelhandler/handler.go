@@ -0,0 +1,288 @@
+// Package levelhandler provides HTTP handler to view and change
+// structlog log levels at runtime.
+//
+// GET returns current level as JSON:
+//
+//	{"level":"inf","units":{"db":{"unit":"db","level":"dbg"}}}
+//
+// PUT changes level using "level", optional "unit" and optional "ttl"
+// ([time.ParseDuration] format) params provided either as a JSON object
+// in request body (with Content-Type: application/json) or as URL query
+// or form values. If "ttl" is given then level will be reverted to
+// previous value after ttl:
+//
+//	curl -X PUT 'localhost:8080/loglevel?unit=db&level=debug&ttl=5m'
+//
+// Level names are same as accepted by [structlog.ParseLevel].
+//
+// Handler created using New allows to change levels of units added using
+// AddUnit only. Handler created using NewForLogger also allows to change
+// level of any other unit: it'll be set using
+// [structlog.Logger.SetUnitLevelVar] on first PUT for that unit (this
+// affects all loggers created using the logger, even already used ones).
+// If such PUT has "ttl" then level override will be removed after ttl,
+// so unit will use logger's level again.
+package levelhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/powerman/structlog"
+)
+
+// Errors.
+var (
+	ErrUnknownLevel = errors.New("unknown level")
+	ErrUnknownUnit  = errors.New("unknown unit")
+	ErrBadTTL       = errors.New("ttl must be positive duration")
+)
+
+// Handler is an [http.Handler] which allows to view and change levels of
+// [structlog.LevelVar].
+type Handler struct {
+	mu        sync.Mutex
+	log       *structlog.Logger // Used to add unknown units, if not nil.
+	level     *structlog.LevelVar
+	units     map[string]*structlog.LevelVar
+	overrides map[*structlog.LevelVar]*override
+}
+
+// override contains details about temporary level change.
+type override struct {
+	timer    *time.Timer
+	revertTo structlog.Level
+	revertAt time.Time
+	unit     string
+	remove   bool // Remove unit's level override instead of reverting.
+}
+
+type request struct {
+	Unit  string `json:"unit"`
+	Level string `json:"level"`
+	TTL   string `json:"ttl"`
+}
+
+type response struct {
+	Unit     string              `json:"unit,omitempty"`
+	Level    string              `json:"level"`
+	RevertTo string              `json:"revert_to,omitempty"`
+	RevertAt time.Time           `json:"revert_at,omitzero"`
+	Units    map[string]response `json:"units,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// New returns a new handler which allows to view and change level.
+//
+// Usually level is a level of structlog.DefaultLogger:
+//
+//	http.Handle("/loglevel", levelhandler.New(structlog.DefaultLogger.LevelVar()))
+func New(level *structlog.LevelVar) *Handler {
+	if level == nil {
+		panic("level must not be nil")
+	}
+	return &Handler{
+		level:     level,
+		units:     make(map[string]*structlog.LevelVar),
+		overrides: make(map[*structlog.LevelVar]*override),
+	}
+}
+
+// NewForLogger returns a new handler which allows to view and change
+// level of l and levels of l's units. Unit without level override uses
+// l's level until it's changed by PUT, which creates a new override for
+// this unit using [structlog.Logger.SetUnitLevelVar].
+//
+// Usually l is structlog.DefaultLogger:
+//
+//	http.Handle("/loglevel", levelhandler.NewForLogger(structlog.DefaultLogger))
+func NewForLogger(l *structlog.Logger) *Handler {
+	if l == nil {
+		panic("logger must not be nil")
+	}
+	h := New(l.LevelVar())
+	h.log = l
+	return h
+}
+
+// AddUnit allows to view and change level of given unit (value of
+// structlog.KeyUnit) using "unit" param.
+//
+// It returns h just for convenience.
+func (h *Handler) AddUnit(unit string, level *structlog.LevelVar) *Handler {
+	if level == nil {
+		panic("level must not be nil")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.units[unit] = level
+	return h
+}
+
+// ServeHTTP implements [http.Handler].
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	var err error
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		req.Unit = r.URL.Query().Get("unit")
+	case http.MethodPut:
+		req, err = parseRequest(r)
+		if err == nil {
+			err = h.set(req)
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		writeJSON(w, http.StatusMethodNotAllowed, response{Error: http.StatusText(http.StatusMethodNotAllowed)})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, response{Error: err.Error()})
+		return
+	}
+
+	resp, err := h.get(req.Unit)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, response{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func parseRequest(r *http.Request) (req request, err error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return req, fmt.Errorf("bad JSON: %w", err)
+		}
+		return req, nil
+	}
+	err = r.ParseForm()
+	if err != nil {
+		return req, err
+	}
+	req.Unit = r.Form.Get("unit")
+	req.Level = r.Form.Get("level")
+	req.TTL = r.Form.Get("ttl")
+	return req, nil
+}
+
+func (h *Handler) set(req request) error {
+	level, err := structlog.ParseLevelStrict(req.Level)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrUnknownLevel, req.Level)
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("%w: %q", ErrBadTTL, req.TTL)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, created, err := h.levelVar(req.Unit, true)
+	if err != nil {
+		return err
+	}
+
+	o := h.overrides[v]
+	switch {
+	case ttl == 0 && o != nil:
+		o.timer.Stop()
+		delete(h.overrides, v)
+	case ttl != 0 && o == nil:
+		o = &override{revertTo: v.Level(), unit: req.Unit, remove: created}
+		h.overrides[v] = o
+	case ttl != 0:
+		o.timer.Stop()
+	}
+	if o != nil && ttl != 0 {
+		o.revertAt = time.Now().Add(ttl)
+		o.timer = time.AfterFunc(ttl, func() { h.revert(v, o) })
+	}
+	v.Set(level)
+	return nil
+}
+
+func (h *Handler) revert(v *structlog.LevelVar, o *override) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.overrides[v] != o {
+		return // Override was replaced while timer was firing.
+	}
+	delete(h.overrides, v)
+	if o.remove {
+		delete(h.units, o.unit)
+		h.log.SetUnitLevelVar(o.unit, nil)
+	} else {
+		v.Set(o.revertTo)
+	}
+}
+
+func (h *Handler) get(unit string) (response, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, _, err := h.levelVar(unit, false)
+	if err != nil {
+		return response{}, err
+	}
+	resp := h.response(unit, v)
+	if unit == "" && len(h.units) > 0 {
+		resp.Units = make(map[string]response, len(h.units))
+		for u, v := range h.units {
+			resp.Units[u] = h.response(u, v)
+		}
+	}
+	return resp, nil
+}
+
+func (h *Handler) response(unit string, v *structlog.LevelVar) response {
+	resp := response{Unit: unit, Level: v.String()}
+	if o := h.overrides[v]; o != nil {
+		resp.RevertTo = o.revertTo.String()
+		resp.RevertAt = o.revertAt
+	}
+	return resp
+}
+
+// levelVar returns level variable for unit. If h.log is set and unit
+// has no level override then it returns h.level or (if create is true)
+// creates an override and returns created true.
+//
+// It must be called with h.mu locked.
+func (h *Handler) levelVar(unit string, create bool) (_ *structlog.LevelVar, created bool, _ error) {
+	if unit == "" {
+		return h.level, false, nil
+	}
+	if v, ok := h.units[unit]; ok {
+		return v, false, nil
+	}
+	switch {
+	case h.log == nil:
+		return nil, false, fmt.Errorf("%w: %q", ErrUnknownUnit, unit)
+	case h.log.UnitLevelVar(unit) != nil:
+		h.units[unit] = h.log.UnitLevelVar(unit)
+	case create:
+		h.units[unit] = structlog.NewLevelVar(h.level.Level())
+		h.log.SetUnitLevelVar(unit, h.units[unit])
+		created = true
+	default:
+		return h.level, false, nil
+	}
+	return h.units[unit], created, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,192 @@
+package levelhandler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+	"github.com/powerman/structlog/levelhandler"
+)
+
+func TestMain(m *testing.M) { check.TestMain(m) }
+
+func serve(t *check.C, h http.Handler, method, target, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if strings.HasPrefix(body, "{") {
+		req.Header.Set("Content-Type", "application/json")
+	} else if body != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	t.Equal(rec.Header().Get("Content-Type"), "application/json")
+	var resp map[string]any
+	t.Nil(json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestHandler(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	level := structlog.NewLevelVar(structlog.INF)
+	db := structlog.NewLevelVar(structlog.WRN)
+	h := levelhandler.New(level).AddUnit("db", db)
+
+	code, resp := serve(t, h, http.MethodGet, "/", "")
+	t.Equal(code, http.StatusOK)
+	t.DeepEqual(resp, map[string]any{
+		"level": "inf",
+		"units": map[string]any{"db": map[string]any{"unit": "db", "level": "WRN"}},
+	})
+	code, resp = serve(t, h, http.MethodGet, "/?unit=db", "")
+	t.Equal(code, http.StatusOK)
+	t.DeepEqual(resp, map[string]any{"unit": "db", "level": "WRN"})
+
+	code, resp = serve(t, h, http.MethodPut, "/?level=debug", "")
+	t.Equal(code, http.StatusOK)
+	t.Equal(resp["level"], "dbg")
+	t.Equal(level.Level(), structlog.DBG)
+	code, _ = serve(t, h, http.MethodPut, "/", "level=error&unit=db")
+	t.Equal(code, http.StatusOK)
+	t.Equal(db.Level(), structlog.ERR)
+	code, resp = serve(t, h, http.MethodPut, "/", `{"level":"Info","unit":"db"}`)
+	t.Equal(code, http.StatusOK)
+	t.DeepEqual(resp, map[string]any{"unit": "db", "level": "inf"})
+	t.Equal(db.Level(), structlog.INF)
+}
+
+func TestNewForLogger(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf strings.Builder
+	log := structlog.New(structlog.KeyUnit, nil, structlog.KeyFunc, nil, structlog.KeySource, nil).
+		SetOutput(&buf).SetLogLevel(structlog.INF).SetUnitLevel("http", structlog.WRN)
+	h := levelhandler.NewForLogger(log)
+
+	code, resp := serve(t, h, http.MethodGet, "/?unit=db", "")
+	t.Equal(code, http.StatusOK)
+	t.DeepEqual(resp, map[string]any{"unit": "db", "level": "inf"})
+	code, resp = serve(t, h, http.MethodGet, "/?unit=http", "")
+	t.Equal(code, http.StatusOK)
+	t.DeepEqual(resp, map[string]any{"unit": "http", "level": "WRN"})
+
+	code, resp = serve(t, h, http.MethodPut, "/?unit=db&level=debug", "")
+	t.Equal(code, http.StatusOK)
+	t.DeepEqual(resp, map[string]any{"unit": "db", "level": "dbg"})
+	t.Equal(log.UnitLevelVar("db").Level(), structlog.DBG)
+	log.Debug("skip")
+	log.Debug("db", structlog.KeyUnit, "db")
+	t.Equal(buf.String(), "levelhandler.test["+strconv.Itoa(os.Getpid())+"] dbg db: `db`\n")
+
+	_, resp = serve(t, h, http.MethodGet, "/", "")
+	t.DeepEqual(resp, map[string]any{
+		"level": "inf",
+		"units": map[string]any{
+			"db":   map[string]any{"unit": "db", "level": "dbg"},
+			"http": map[string]any{"unit": "http", "level": "WRN"},
+		},
+	})
+
+	t.Panic(func() { levelhandler.NewForLogger(nil) })
+}
+
+func TestHandlerErrors(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	level := structlog.NewLevelVar(structlog.INF)
+	h := levelhandler.New(level)
+
+	code, resp := serve(t, h, http.MethodPost, "/?level=debug", "")
+	t.Equal(code, http.StatusMethodNotAllowed)
+	t.NotZero(resp["error"])
+	code, resp = serve(t, h, http.MethodGet, "/?unit=nope", "")
+	t.Equal(code, http.StatusNotFound)
+	t.Match(resp["error"], "unknown unit")
+	code, resp = serve(t, h, http.MethodPut, "/?level=nope", "")
+	t.Equal(code, http.StatusBadRequest)
+	t.Match(resp["error"], "unknown level")
+	code, resp = serve(t, h, http.MethodPut, "/?level=debug&unit=nope", "")
+	t.Equal(code, http.StatusBadRequest)
+	t.Match(resp["error"], "unknown unit")
+	code, resp = serve(t, h, http.MethodPut, "/?level=debug&ttl=-1s", "")
+	t.Equal(code, http.StatusBadRequest)
+	t.Match(resp["error"], "ttl")
+	code, resp = serve(t, h, http.MethodPut, "/", `{"level":`)
+	t.Equal(code, http.StatusBadRequest)
+	t.Match(resp["error"], "bad JSON")
+	t.Equal(level.Level(), structlog.INF)
+
+	t.Panic(func() { levelhandler.New(nil) })
+	t.Panic(func() { h.AddUnit("db", nil) })
+}
+
+func TestHandlerTTL(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	level := structlog.NewLevelVar(structlog.INF)
+	h := levelhandler.New(level)
+
+	code, resp := serve(t, h, http.MethodPut, "/?level=debug&ttl=1h", "")
+	t.Equal(code, http.StatusOK)
+	t.Equal(resp["level"], "dbg")
+	t.Equal(resp["revert_to"], "inf")
+	t.NotZero(resp["revert_at"])
+	_, resp = serve(t, h, http.MethodPut, "/?level=warn&ttl=50ms", "")
+	t.Equal(resp["level"], "WRN")
+	t.Equal(resp["revert_to"], "inf", "keep level before first override")
+	t.Equal(level.Level(), structlog.WRN)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for level.Level() != structlog.INF && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Equal(level.Level(), structlog.INF)
+	_, resp = serve(t, h, http.MethodGet, "/", "")
+	t.DeepEqual(resp, map[string]any{"level": "inf"})
+
+	serve(t, h, http.MethodPut, "/?level=debug&ttl=50ms", "")
+	serve(t, h, http.MethodPut, "/?level=error", "")
+	time.Sleep(100 * time.Millisecond)
+	t.Equal(level.Level(), structlog.ERR, "permanent change cancels revert")
+}
+
+func TestNewForLoggerUsedChild(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf strings.Builder
+	log := structlog.New(structlog.KeyFunc, nil, structlog.KeySource, nil).
+		SetOutput(&buf).SetLogLevel(structlog.INF)
+	db := log.New(structlog.KeyUnit, "db")
+	db.Debug("skip")
+	h := levelhandler.NewForLogger(log)
+
+	code, resp := serve(t, h, http.MethodPut, "/?unit=db&level=debug&ttl=50ms", "")
+	t.Equal(code, http.StatusOK)
+	t.Equal(resp["level"], "dbg")
+	t.Equal(resp["revert_to"], "inf")
+	db.Debug("shown")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for log.UnitLevelVar("db") != nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Nil(log.UnitLevelVar("db"), "override removed")
+	db.Debug("skip")
+	_, resp = serve(t, h, http.MethodGet, "/?unit=db", "")
+	t.DeepEqual(resp, map[string]any{"unit": "db", "level": "inf"})
+
+	serve(t, h, http.MethodPut, "/?level=debug", "")
+	db.Debug("follows global level")
+	t.Equal(buf.String(), ""+
+		"levelhandler.test["+strconv.Itoa(os.Getpid())+"] dbg db: `shown`\n"+
+		"levelhandler.test["+strconv.Itoa(os.Getpid())+"] dbg db: `follows global level`\n")
+}
@@ -0,0 +1,154 @@
+package structlog
+
+import (
+	"maps"
+	"slices"
+	"sync/atomic"
+)
+
+// LevelVar is a log level variable, to allow a Logger level to change
+// dynamically. It is safe for use by multiple goroutines.
+//
+// Use Logger.SetLevelVar to bind a logger (and all loggers created using
+// it's New method) to a LevelVar.
+//
+// The zero LevelVar corresponds to DBG.
+type LevelVar struct {
+	level atomic.Int32 // Relative to DBG to make zero value DBG.
+}
+
+// NewLevelVar returns a new LevelVar with given level.
+func NewLevelVar(level Level) *LevelVar {
+	v := &LevelVar{}
+	v.Set(level)
+	return v
+}
+
+// Level returns v's level.
+func (v *LevelVar) Level() Level {
+	return Level(v.level.Load() + int32(DBG)) //nolint:gosec // Always set from Level.
+}
+
+// Set sets v's level to level.
+func (v *LevelVar) Set(level Level) {
+	v.level.Store(int32(level) - int32(DBG))
+}
+
+// String returns v's level name.
+func (v *LevelVar) String() string {
+	return v.Level().String()
+}
+
+// levelVars contains level variables used by Logger after mergeParent.
+// It's immutable, so it can be used without locking Logger.
+type levelVars struct {
+	level *LevelVar
+	units *unitLevels
+}
+
+func newLevelVars(level *LevelVar, units *unitLevels) *levelVars {
+	return &levelVars{level: level, units: units}
+}
+
+// mayBeEnabled returns true if record with given level will be output
+// by any unit.
+func (lv *levelVars) mayBeEnabled(level Level) bool {
+	return level >= lv.level.Level() || lv.units.mayBeEnabled(level)
+}
+
+// unitLevel returns minimum required log level for records with given
+// unit. If hasUnit is false then unit is ignored.
+func (lv *levelVars) unitLevel(unit string, hasUnit bool) Level {
+	if v := lv.units.get(unit); v != nil && hasUnit {
+		return v.Level()
+	}
+	return lv.level.Level()
+}
+
+// unitLevels contains per-unit level overrides set on a Logger. Units
+// without own override use overrides of parent Logger, which are looked
+// up on each use, so changes made on parent affect already used children.
+type unitLevels struct {
+	parent *unitLevels
+	own    atomic.Pointer[unitLevelsMap] // Nil if empty.
+}
+
+// unitLevelsMap is an immutable set of unitLevels overrides.
+type unitLevelsMap struct {
+	units map[string]*LevelVar // Nil value removes parent's override.
+	all   []*LevelVar          // Non-nil values of units.
+}
+
+// set adds override for unit (removes parent's override if v is nil).
+// Calls must be serialized by caller.
+func (u *unitLevels) set(unit string, v *LevelVar) {
+	m := &unitLevelsMap{units: make(map[string]*LevelVar)}
+	if old := u.own.Load(); old != nil {
+		maps.Copy(m.units, old.units)
+	}
+	m.units[unit] = v
+	for _, v := range m.units {
+		if v != nil {
+			m.all = append(m.all, v)
+		}
+	}
+	u.own.Store(m)
+}
+
+// get returns level variable for unit or nil if unit has no override.
+func (u *unitLevels) get(unit string) *LevelVar {
+	for ; u != nil; u = u.parent {
+		if m := u.own.Load(); m != nil {
+			if v, ok := m.units[unit]; ok {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// isEmpty returns true if there are no overrides.
+func (u *unitLevels) isEmpty() bool {
+	for ; u != nil; u = u.parent {
+		if m := u.own.Load(); m != nil && len(m.all) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// mayBeEnabled returns true if record with given level will be output
+// by any unit with override.
+func (u *unitLevels) mayBeEnabled(level Level) bool {
+	for ; u != nil; u = u.parent {
+		if m := u.own.Load(); m != nil {
+			for _, v := range m.all {
+				if level >= v.Level() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// vars returns effective overrides.
+func (u *unitLevels) vars() map[string]*LevelVar {
+	var chain []*unitLevelsMap
+	for ; u != nil; u = u.parent {
+		if m := u.own.Load(); m != nil {
+			chain = append(chain, m)
+		}
+	}
+	units := make(map[string]*LevelVar)
+	for _, m := range slices.Backward(chain) {
+		for unit, v := range m.units {
+			if v != nil {
+				units[unit] = v
+			} else {
+				delete(units, unit)
+			}
+		}
+	}
+	return units
+}
@@ -0,0 +1,40 @@
+package structlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestLevelVar(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var zero structlog.LevelVar
+	t.Equal(zero.Level(), structlog.DBG)
+	lv := structlog.NewLevelVar(structlog.INF)
+	t.Equal(lv.String(), "inf")
+
+	var buf bytes.Buffer
+	parent := structlog.New().SetOutput(&buf).SetLevelVar(lv)
+	child := parent.New()
+	child.Debug("skip")
+	own := parent.New().SetLogLevel(structlog.ERR)
+	t.Equal(child.LevelVar(), lv)
+	t.Equal(parent.LevelVar(), lv)
+	t.NotEqual(own.LevelVar(), lv)
+
+	lv.Set(structlog.DBG)
+	t.True(parent.IsDebug())
+	t.True(child.IsDebug())
+	t.False(own.IsInfo())
+	child.Debug("dump")
+	own.Warn("skip")
+	t.Match(buf.String(), "^[^\n]*`dump`[^\n]*\n$")
+
+	lv.Set(structlog.WRN)
+	t.False(child.IsInfo())
+	t.Panic(func() { parent.SetLevelVar(nil) })
+}
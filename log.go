@@ -1,7 +1,9 @@
 package structlog
 
 import (
+	"encoding"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,6 +14,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,22 +23,35 @@ func init() { //nolint:gochecknoinits // Reset defaults once.
 }
 
 type (
-	logFormat byte
-	logLevel  byte
+	// Format is a log output format. It implements [flag.Value],
+	// [encoding.TextMarshaler] and [encoding.TextUnmarshaler], so it can be
+	// used with [flag.Var] and in config structs.
+	Format byte
+	// Level is a log level. It implements [flag.Value],
+	// [encoding.TextMarshaler] and [encoding.TextUnmarshaler], so it can be
+	// used with [flag.Var] and in config structs.
+	Level int8
 )
 
 // Log formats.
 const (
-	Text logFormat = iota
+	Text Format = iota
 	JSON
+	Logfmt
 )
 
 // Log levels.
+//
+// Values are same as values of related [slog.Level] (TRC is 4 below
+// [slog.LevelDebug], CRT is 4 above [slog.LevelError]). Custom levels
+// between them may be added using RegisterLevel.
 const (
-	DBG logLevel = iota
-	INF
-	WRN
-	ERR
+	TRC Level = -8
+	DBG Level = -4
+	INF Level = 0
+	WRN Level = 4
+	ERR Level = 8
+	CRT Level = 12
 )
 
 // Defaults.
@@ -45,6 +61,8 @@ const (
 	DefaultKeyValFormat  = ` %s=%v`
 	DefaultTimeFormat    = time.StampMicro
 	DefaultTimeValFormat = time.RFC3339Nano
+	DefaultTypedJSON     = false
+	DefaultSanitize      = false
 	MissingValue         = "(MISSING)"
 )
 
@@ -80,25 +98,118 @@ type PrinterFunc func(v ...any)
 // Print outputs v plus \n. Arguments are handled in the manner of [fmt.Print].
 func (f PrinterFunc) Print(v ...any) { f(v...) }
 
-// ParseLevel convert levelName from flag or config file into logLevel.
-func ParseLevel(levelName string) logLevel { //nolint:revive // Intentionally return unexported.
-	switch strings.ToLower(levelName) {
-	case "err", "error", "fatal", "crit", "critical", "alert", "emerg", "emergency":
-		return ERR
+// Errors.
+var (
+	ErrUnknownLevel  = errors.New("unknown log level")
+	ErrUnknownFormat = errors.New("unknown log format")
+)
+
+// ParseLevel convert levelName from flag or config file into Level.
+func ParseLevel(levelName string) Level {
+	level, err := ParseLevelStrict(levelName)
+	if err != nil {
+		DefaultLogger.PrintErr("failed", "levelName", levelName)
+		return DBG
+	}
+	return level
+}
+
+// ParseLevelStrict is like ParseLevel but returns ErrUnknownLevel for
+// unknown levelName.
+func ParseLevelStrict(levelName string) (Level, error) {
+	name := strings.ToLower(levelName)
+	customLevels.mu.RLock()
+	level, ok := customLevels.byName[name]
+	customLevels.mu.RUnlock()
+	if ok {
+		return level, nil
+	}
+	switch name {
+	case "crt", "crit", "critical", "fatal", "alert", "emerg", "emergency":
+		return CRT, nil
+	case "err", "error":
+		return ERR, nil
 	case "wrn", "warn", "warning":
-		return WRN
+		return WRN, nil
 	case "inf", "info", "notice":
-		return INF
-	case "dbg", "debug", "trace":
-		return DBG
+		return INF, nil
+	case "dbg", "debug":
+		return DBG, nil
+	case "trc", "trace":
+		return TRC, nil
 	default:
-		DefaultLogger.PrintErr("failed", "levelName", levelName)
-		return DBG
+		return DBG, ErrUnknownLevel
 	}
 }
 
-func (l logLevel) String() string {
+// overridableLevelNames are syslog severity names which are aliases for
+// predefined levels but may be used by RegisterLevel.
+var overridableLevelNames = map[string]bool{"notice": true, "alert": true, "emerg": true, "emergency": true} //nolint:gochecknoglobals // Const.
+
+// customLevels contains levels added by RegisterLevel.
+var customLevels struct { //nolint:gochecknoglobals // Registry.
+	mu     sync.RWMutex
+	byName map[string]Level // Lowercased name.
+	names  map[Level]string
+}
+
+// RegisterLevel adds custom level with given name. Name will be used to
+// output level and it will be accepted (in any case) by ParseLevel.
+// Like names of predefined levels it should be 3 characters long to
+// keep Text output aligned.
+//
+//	const NTC structlog.Level = structlog.INF + 2
+//	func init() { structlog.RegisterLevel(NTC, "NTC") }
+//
+// Use Logger.Log to log with custom level.
+//
+// Name may be one of syslog severity names which ParseLevel accepts as
+// aliases for predefined levels ("notice", "alert", "emerg" and
+// "emergency"), then ParseLevel will return registered level for it:
+//
+//	func init() { structlog.RegisterLevel(structlog.CRT+4, "alert") }
+//
+// It panics if level or name is already used by predefined or registered
+// level. It should be called from init() before any logging.
+func RegisterLevel(level Level, name string) {
+	if level.String() != unknown {
+		panic(fmt.Sprintf("level %d is already registered as %q", level, level.String()))
+	}
+	lowerName := strings.ToLower(name)
+	_, err := ParseLevelStrict(name)
+	customLevels.mu.Lock()
+	defer customLevels.mu.Unlock()
+	_, isCustom := customLevels.byName[lowerName]
+	if err == nil && (isCustom || !overridableLevelNames[lowerName]) || name == "" || name == unknown {
+		panic(fmt.Sprintf("level name %q is already used", name))
+	}
+	if customLevels.names == nil {
+		customLevels.byName = make(map[string]Level)
+		customLevels.names = make(map[Level]string)
+	}
+	customLevels.byName[lowerName] = level
+	customLevels.names[level] = name
+}
+
+// ParseFormat convert formatName (one of: text, json, logfmt) into Format
+// or returns ErrUnknownFormat.
+func ParseFormat(formatName string) (Format, error) {
+	switch strings.ToLower(formatName) {
+	case "text":
+		return Text, nil
+	case "json":
+		return JSON, nil
+	case "logfmt":
+		return Logfmt, nil
+	default:
+		return Text, ErrUnknownFormat
+	}
+}
+
+func (l Level) String() string {
 	switch l {
+	case CRT:
+		return "CRT"
 	case ERR:
 		return "ERR"
 	case WRN:
@@ -107,30 +218,123 @@ func (l logLevel) String() string {
 		return "inf"
 	case DBG:
 		return "dbg"
+	case TRC:
+		return "trc"
 	default:
+		customLevels.mu.RLock()
+		defer customLevels.mu.RUnlock()
+		if name, ok := customLevels.names[l]; ok {
+			return name
+		}
 		return unknown
 	}
 }
 
-func (l logLevel) MarshalJSON() ([]byte, error) {
+func (l Level) MarshalJSON() ([]byte, error) {
 	return []byte(`"` + l.String() + `"`), nil
 }
 
+// MarshalText implements [encoding.TextMarshaler].
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+// It accepts same names as ParseLevel.
+func (l *Level) UnmarshalText(text []byte) error {
+	return l.Set(string(text))
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+// It accepts JSON string with same names as ParseLevel.
+func (l *Level) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, l)
+}
+
+// Set implements [flag.Value].
+// It accepts same names as ParseLevel.
+func (l *Level) Set(levelName string) error {
+	level, err := ParseLevelStrict(levelName)
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, levelName)
+	}
+	*l = level
+	return nil
+}
+
+func (f Format) String() string {
+	switch f {
+	case Text:
+		return "text"
+	case JSON:
+		return "json"
+	case Logfmt:
+		return "logfmt"
+	default:
+		return unknown
+	}
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (f Format) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+// It accepts same names as ParseFormat.
+func (f *Format) UnmarshalText(text []byte) error {
+	return f.Set(string(text))
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+// It accepts JSON string with same names as ParseFormat.
+func (f *Format) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, f)
+}
+
+// Set implements [flag.Value].
+// It accepts same names as ParseFormat.
+func (f *Format) Set(formatName string) error {
+	format, err := ParseFormat(formatName)
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, formatName)
+	}
+	*f = format
+	return nil
+}
+
+// unmarshalJSONText unmarshals JSON string in data using v.
+func unmarshalJSONText(data []byte, v encoding.TextUnmarshaler) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return v.UnmarshalText([]byte(s))
+}
+
 // Logger implements structured logger.
 type Logger struct {
 	mu             sync.RWMutex
 	parent         *Logger
 	printer        Printer
-	format         *logFormat
-	level          *logLevel
+	format         *Format
+	level          *LevelVar
 	keyValFormat   *string
 	timeFormat     *string
 	timeValFormat  *string
+	typedJSON      *bool
+	color          *Color
+	messageWidth   *int
+	sanitize       *bool
 	callDepth      int
 	defaultKeyvals map[string]any
 	prefixKeys     []string
 	suffixKeys     []string
 	keysFormat     map[string]string
+	unitLevels     *unitLevels
+	outputs        []*Logger
+	layout         *layout                   // Calculated from other fields after mergeParent.
+	levels         atomic.Pointer[levelVars] // Calculated from level and unitLevels after mergeParent.
 }
 
 // getAppName returns the application name without path and .exe extension.
@@ -170,15 +374,23 @@ func NewZeroLogger(defaultKeyvals ...any) *Logger {
 		keyValFormat  = DefaultKeyValFormat
 		timeFormat    = DefaultTimeFormat
 		timeValFormat = DefaultTimeValFormat
+		typedJSON     = DefaultTypedJSON
+		color         = DefaultColor
+		messageWidth  = DefaultMessageWidth
+		sanitize      = DefaultSanitize
 	)
-	return (&Logger{
+	root := &Logger{
 		parent:        nil,
-		printer:       PrinterFunc(log.Print),
+		printer:       stdLogPrinter{},
 		format:        &format,
-		level:         &level,
+		level:         NewLevelVar(level),
 		keyValFormat:  &keyValFormat,
 		timeFormat:    &timeFormat,
 		timeValFormat: &timeValFormat,
+		typedJSON:     &typedJSON,
+		color:         &color,
+		messageWidth:  &messageWidth,
+		sanitize:      &sanitize,
 		callDepth:     2, //nolint:mnd // Public method like Err() or Recover() plus l.log().
 		defaultKeyvals: map[string]any{
 			KeyUnit:   Auto,    // must be non-nil to enable field
@@ -188,7 +400,11 @@ func NewZeroLogger(defaultKeyvals ...any) *Logger {
 		prefixKeys: []string{},
 		suffixKeys: []string{},
 		keysFormat: make(map[string]string),
-	}).New(defaultKeyvals...)
+		unitLevels: &unitLevels{},
+	}
+	root.layout = root.newLayout()
+	root.levels.Store(newLevelVars(root.level, root.unitLevels))
+	return root.New(defaultKeyvals...)
 }
 
 // New creates and returns a new logger which inherits all settings from
@@ -203,40 +419,82 @@ func (l *Logger) New(defaultKeyvals ...any) *Logger {
 		panic("New called on nil *Logger")
 	}
 	const sizeHint = 16
-	return (&Logger{
+	child := &Logger{
 		parent:         l,
 		callDepth:      0,
 		defaultKeyvals: make(map[string]any, sizeHint),
 		prefixKeys:     make([]string, 0, sizeHint),
 		suffixKeys:     make([]string, 0, sizeHint),
 		keysFormat:     make(map[string]string, sizeHint),
-	}).SetDefaultKeyvals(defaultKeyvals...)
+		unitLevels:     &unitLevels{parent: l.unitLevels},
+	}
+	return child.SetDefaultKeyvals(defaultKeyvals...)
 }
 
-// SetPrinter changes log output destination (default value is
-// PrinterFunc(log.Print), i.e. use standard logger, which will be
-// configured using [log.SetFlags](0) while importing this package).
+// SetPrinter changes log output destination (default value is Printer
+// which works like PrinterFunc(log.Print), i.e. use standard logger, which
+// will be configured using [log.SetFlags](0) while importing this
+// package).
+//
+// It also makes l ignore outputs set by SetOutputs (including inherited).
 //
 // It doesn't creates a new logger, it returns l just for convenience.
 func (l *Logger) SetPrinter(printer Printer) *Logger {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.printer = printer
+	l.outputs = []*Logger{}
 	return l
 }
 
 // SetOutput is a convenience wrapper for SetPrinter.
+//
+// Log records will be written to w without extra allocations needed to
+// call Printer.
 func (l *Logger) SetOutput(w io.Writer) *Logger {
-	return l.SetPrinter(PrinterFunc(func(v ...any) {
-		_, _ = fmt.Fprint(w, append(v, "\n")...)
-	}))
+	return l.SetPrinter(newWriterPrinter(w))
+}
+
+// writerPrinter is a Printer used by SetOutput.
+type writerPrinter struct {
+	w   io.Writer
+	tty bool // Used by ColorAuto.
+}
+
+func newWriterPrinter(w io.Writer) writerPrinter {
+	return writerPrinter{w: w, tty: isTerminalWriter(w)}
+}
+
+// Print outputs v plus \n. Arguments are handled in the manner of [fmt.Print].
+func (p writerPrinter) Print(v ...any) { _, _ = fmt.Fprint(p.w, append(v, "\n")...) }
+
+// stdLogPrinter is a default Printer which outputs using standard logger.
+type stdLogPrinter struct{}
+
+// Print outputs v using [log.Print].
+func (stdLogPrinter) Print(v ...any) { log.Print(v...) }
+
+// printLine outputs line with given level using p. Line may be modified.
+func printLine(p Printer, level Level, line []byte) {
+	switch p := p.(type) {
+	case writerPrinter:
+		_, _ = p.w.Write(append(line, '\n'))
+	case levelPrinter:
+		p.printLevel(level, line)
+	default:
+		p.Print(string(line))
+	}
 }
 
 // SetLogFormat changes log output format (default value is
 // DefaultLogFormat).
 //
+// Text format is configured using SetKeyValFormat, SetKeysFormat and
+// SetTimeFormat. JSON and Logfmt formats always include KeyTime (in UTC)
+// and KeyLevel, and output keys in same order as Text.
+//
 // It doesn't creates a new logger, it returns l just for convenience.
-func (l *Logger) SetLogFormat(format logFormat) *Logger {
+func (l *Logger) SetLogFormat(format Format) *Logger {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.format = &format
@@ -246,14 +504,83 @@ func (l *Logger) SetLogFormat(format logFormat) *Logger {
 // SetLogLevel changes minimum required log level to output log
 // (default value is DefaultLogLevel).
 //
+// It won't affect loggers created using l.New() which was already used
+// to log anything, use SetLevelVar if you need to change their level.
+//
 // It doesn't creates a new logger, it returns l just for convenience.
-func (l *Logger) SetLogLevel(level logLevel) *Logger {
+func (l *Logger) SetLogLevel(level Level) *Logger {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.level = &level
+	return l.setLevelVar(NewLevelVar(level))
+}
+
+// SetLevelVar binds l to v, so changing v's level using [LevelVar.Set]
+// will immediately change minimum required log level for l and all
+// loggers created using l.New() which didn't change their level.
+//
+// It doesn't creates a new logger, it returns l just for convenience.
+func (l *Logger) SetLevelVar(v *LevelVar) *Logger {
+	if v == nil {
+		panic("SetLevelVar called with nil *LevelVar")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.setLevelVar(v)
+}
+
+// setLevelVar must be called with l.mu locked.
+func (l *Logger) setLevelVar(v *LevelVar) *Logger {
+	l.level = v
+	if l.parent == nil {
+		l.levels.Store(newLevelVars(l.level, l.unitLevels))
+	}
+	return l
+}
+
+// LevelVar returns level variable used by l. It may be shared with other
+// loggers (see SetLevelVar).
+func (l *Logger) LevelVar() *LevelVar {
+	l.enabled(DBG) // Call mergeParent.
+	return l.levels.Load().level
+}
+
+// SetUnitLevel changes minimum required log level to output log for
+// records with given KeyUnit value, overriding level set by SetLogLevel
+// for this unit.
+//
+// Record's unit is KeyUnit value from keyvals or defaultKeyvals. If it's
+// Auto then unit is calculated from caller's package directory (same as
+// will be output). Records without KeyUnit use level set by SetLogLevel.
+//
+// It affects all loggers created using l.New() (even if they was already
+// used to log anything) unless they have own level override for this
+// unit.
+//
+// It doesn't creates a new logger, it returns l just for convenience.
+func (l *Logger) SetUnitLevel(unit string, level Level) *Logger {
+	return l.SetUnitLevelVar(unit, NewLevelVar(level))
+}
+
+// SetUnitLevelVar is like SetLevelVar but for records with given
+// KeyUnit value (see SetUnitLevel).
+//
+// If v is nil then level override for unit (including inherited from
+// parent) will be removed.
+//
+// It doesn't creates a new logger, it returns l just for convenience.
+func (l *Logger) SetUnitLevelVar(unit string, v *LevelVar) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.unitLevels.set(unit, v)
 	return l
 }
 
+// UnitLevelVar returns level variable used by l for records with given
+// KeyUnit value or nil if unit has no level override.
+func (l *Logger) UnitLevelVar(unit string) *LevelVar {
+	return l.unitLevels.get(unit)
+}
+
 // SetKeyValFormat changes fmt format string used to output key/value pair
 // for keys which doesn't have custom format set by SetKeysFormat (default
 // value is DefaultKeyValFormat).
@@ -265,6 +592,9 @@ func (l *Logger) SetKeyValFormat(format string) *Logger {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.keyValFormat = &format
+	if l.parent == nil {
+		l.layout = l.newLayout()
+	}
 	return l
 }
 
@@ -290,6 +620,48 @@ func (l *Logger) SetTimeValFormat(format string) *Logger {
 	return l
 }
 
+// SetTypedJSON changes the way values are output in JSON format (default
+// value is DefaultTypedJSON).
+//
+// By default all values are output as JSON strings, in the manner of
+// [fmt.Sprint]. If typed is true then values will keep their JSON types:
+// numbers, bools, nil, maps, slices and structs will be output using
+// [json.Marshal], errors and [fmt.Stringer] will be output as strings,
+// [time.Duration] will be output as a number of nanoseconds. Values which
+// fail to marshal will be output in the manner of [fmt.Sprint].
+//
+// It doesn't creates a new logger, it returns l just for convenience.
+func (l *Logger) SetTypedJSON(typed bool) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.typedJSON = &typed
+	return l
+}
+
+// SetSanitize changes the way values are output in Text format (default
+// value is DefaultSanitize).
+//
+// If sanitize is true then newlines, tabs, other control characters
+// (including ESC), Unicode line separators and invalid UTF-8 bytes in keys
+// and values will be escaped (like \n, \x1b, \u2028 or \xff), to make
+// sure single log record is output as a single line and can't mess up
+// terminal. Backslashes will be escaped as \\ to keep output unambiguous.
+// Values output using %q are already escaped (back-quoted values are
+// double-quoted if needed). Values of KeyStack are output as is.
+//
+// Keys with format which is handled by package fmt (like " %s=%-5v",
+// see SetKeysFormat) are sanitized after formatting, so format's literal
+// text is sanitized too, but tabs are output as is (to keep tabs used in
+// format).
+//
+// It doesn't creates a new logger, it returns l just for convenience.
+func (l *Logger) SetSanitize(sanitize bool) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sanitize = &sanitize
+	return l
+}
+
 // AddCallDepth will add depth to amount of skipped stack frames while
 // calculating default values for KeyUnit, KeyFunc and KeySource.
 //
@@ -305,7 +677,8 @@ func (l *Logger) AddCallDepth(depth int) *Logger {
 
 // SetDefaultKeyvals add/replace values for keys in defaultKeyvals.
 //
-// The keyvals must be a list of key/value pairs, keys must be a string.
+// The keyvals must be a list of key/value pairs, keys must be a string
+// ([slog.Attr] may be used in place of key/value pair).
 // In case of odd amount of elements in keyvals it'll log error and use
 // MissingValue as value for last key. In case of non-string keys it'll
 // log error and convert key to string.
@@ -320,6 +693,7 @@ func (l *Logger) AddCallDepth(depth int) *Logger {
 //
 // It doesn't creates a new logger, it returns l just for convenience.
 func (l *Logger) SetDefaultKeyvals(keyvals ...any) *Logger {
+	keyvals = expandAttrs(keyvals)
 	if len(keyvals)%2 != 0 {
 		l.New().AddCallDepth(getPackageDepth()).PrintErr("odd keyvals")
 		keyvals = append(keyvals, MissingValue)
@@ -423,31 +797,64 @@ func (l *Logger) SetKeysFormat(keysFormat map[string]string) *Logger {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	maps.Copy(l.keysFormat, keysFormat)
+	if l.parent == nil {
+		l.layout = l.newLayout()
+	}
 	return l
 }
 
-// IsInfo returns true if l's log level DBG or INF.
+// IsInfo returns true if l's log level INF or lower.
+//
+// If l has per-unit levels (see SetUnitLevel) then level for unit of
+// IsInfo's caller is used.
 func (l *Logger) IsInfo() bool {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-	if l.parent != nil {
-		l.mu.RUnlock()
-		l.mergeParent()
-		l.mu.RLock()
-	}
-	return *l.level <= INF
+	return l.isEnabled(INF)
 }
 
-// IsDebug returns true if l's log level DBG.
+// IsDebug returns true if l's log level DBG or lower.
+//
+// If l has per-unit levels (see SetUnitLevel) then level for unit of
+// IsDebug's caller is used.
 func (l *Logger) IsDebug() bool {
+	return l.isEnabled(DBG)
+}
+
+// IsTrace returns true if l's log level TRC or lower.
+//
+// If l has per-unit levels (see SetUnitLevel) then level for unit of
+// IsTrace's caller is used.
+func (l *Logger) IsTrace() bool {
+	return l.isEnabled(TRC)
+}
+
+// isEnabled returns true if l will output log with given level called
+// from same place as isEnabled's caller. It must be called by public
+// method.
+func (l *Logger) isEnabled(level Level) bool {
+	if !l.enabled(level) {
+		return false
+	}
+	lv := l.levels.Load()
+	if lv.units.isEmpty() {
+		return true
+	}
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	if l.parent != nil {
-		l.mu.RUnlock()
+	return level >= lv.unitLevel(l.unit(l.callerPC(0), nil))
+}
+
+// enabled returns true if l may output log with given level (for some
+// unit, if l has per-unit levels).
+//
+// It doesn't acquire l.mu unless l wasn't used yet, so it's cheap enough
+// to be called on each log call.
+func (l *Logger) enabled(level Level) bool {
+	lv := l.levels.Load()
+	if lv == nil {
 		l.mergeParent()
-		l.mu.RLock()
+		lv = l.levels.Load()
 	}
-	return *l.level <= DBG
+	return lv.mayBeEnabled(level)
 }
 
 // Recover calls recover(), and if it returns non-nil, then log
@@ -527,6 +934,13 @@ func (l *Logger) DebugIfFail(f func() error, keyvals ...any) {
 	}
 }
 
+// Crit log defaultKeyvals, msg and keyvals with level CRT and returns
+// first arg of error type or msg if there are no errors in args.
+func (l *Logger) Crit(msg any, keyvals ...any) error {
+	l.log(CRT, msg, keyvals...)
+	return getErr(msg, keyvals...)
+}
+
 // PrintErr log defaultKeyvals, msg and keyvals with level ERR.
 //
 // In most cases you should use Err instead, to both log and handle error.
@@ -561,6 +975,17 @@ func (l *Logger) Debug(msg any, keyvals ...any) {
 	l.log(DBG, msg, keyvals...)
 }
 
+// Trace log defaultKeyvals, msg and keyvals with level TRC.
+func (l *Logger) Trace(msg any, keyvals ...any) {
+	l.log(TRC, msg, keyvals...)
+}
+
+// Log log defaultKeyvals, msg and keyvals with given level.
+// It's useful for custom levels added by RegisterLevel.
+func (l *Logger) Log(level Level, msg any, keyvals ...any) {
+	l.log(level, msg, keyvals...)
+}
+
 // Print works like [log.Print]. Use level INF.
 // Also output defaultKeyvals for prefixKeys/suffixKeys.
 func (l *Logger) Print(v ...any) {
@@ -579,24 +1004,30 @@ func (l *Logger) Println(v ...any) {
 	l.log(INF, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
 }
 
-// Fatal works like [log.Fatal]. Use level ERR.
+// Fatal works like [log.Fatal]. Use level CRT.
 // Also output defaultKeyvals for prefixKeys/suffixKeys.
+// Calls Flush before exit.
 func (l *Logger) Fatal(v ...any) {
-	l.log(ERR, fmt.Sprint(v...))
+	l.log(CRT, fmt.Sprint(v...))
+	l.flushBeforeExit()
 	os.Exit(1) //nolint:revive // By design.
 }
 
-// Fatalf works like [log.Fatalf]. Use level ERR.
+// Fatalf works like [log.Fatalf]. Use level CRT.
 // Also output defaultKeyvals for prefixKeys/suffixKeys.
+// Calls Flush before exit.
 func (l *Logger) Fatalf(format string, v ...any) {
-	l.log(ERR, fmt.Sprintf(format, v...))
+	l.log(CRT, fmt.Sprintf(format, v...))
+	l.flushBeforeExit()
 	os.Exit(1) //nolint:revive // By design.
 }
 
-// Fatalln works like [log.Fatalln]. Use level ERR.
+// Fatalln works like [log.Fatalln]. Use level CRT.
 // Also output defaultKeyvals for prefixKeys/suffixKeys.
+// Calls Flush before exit.
 func (l *Logger) Fatalln(v ...any) {
-	l.log(ERR, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	l.log(CRT, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	l.flushBeforeExit()
 	os.Exit(1) //nolint:revive // By design.
 }
 
@@ -626,155 +1057,193 @@ func (l *Logger) Panicln(v ...any) {
 
 var now = time.Now //nolint:gochecknoglobals // For tests.
 
-func (l *Logger) log(level logLevel, msg any, keyvals ...any) { //nolint:gocyclo,gocognit,funlen // TODO Simplify.
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-	if l.parent != nil {
-		l.mu.RUnlock()
-		l.mergeParent()
-		l.mu.RLock()
-	}
+func (l *Logger) log(level Level, msg any, keyvals ...any) {
+	l.output(level, nil, 0, msg, keyvals...)
+}
 
-	if *l.level > level {
+// output logs msg and keyvals with given level.
+//
+// If t is nil then current time will be used (only if record is enabled,
+// to keep disabled calls cheap), if *t is zero then KeyTime won't be
+// output.
+// If pc is zero then caller's details will be calculated using
+// l.callDepth, which assumes output is called by l.log.
+func (l *Logger) output(level Level, t *time.Time, pc uintptr, msg any, keyvals ...any) { //nolint:gocognit,funlen // TODO Simplify.
+	if !l.enabled(level) { // Also calls mergeParent.
 		return
 	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 
+	keyvals = expandAttrs(keyvals)
+	if lv := l.levels.Load(); !lv.units.isEmpty() {
+		if pc == 0 {
+			pc = l.callerPC(1)
+		}
+		if level < lv.unitLevel(l.unit(pc, keyvals)) {
+			return
+		}
+	}
 	if len(keyvals)%2 != 0 {
 		l.New().AddCallDepth(getPackageDepth()).PrintErr("odd keyvals")
 		keyvals = append(keyvals, MissingValue)
 	}
 
-	keyvals = append(unwrap(getErr(msg, keyvals...)), keyvals...)
+	if errKeyvals := unwrap(findErr(msg, keyvals...)); len(errKeyvals) > 0 {
+		keyvals = append(errKeyvals, keyvals...)
+	}
+	keyvals = resolveKeyvals(keyvals)
 
-	// TODO Combine all of this in single type and use sync.Pool.
-	// Probably several different pools with different key sizes.
-	// Use same len(vals) capability for all slices.
-	// TODO Pre-calculate surroundKeys/prefixFormat/suffixFormat in
-	// places where prefixKeys/suffixKeys may change.
-	const extraKeys = 7 // KeyMessage, KeyTime, KeyLevel, KeyUnit, KeyFunc, KeySource, KeyStack
-	vals := make(kvs, len(l.prefixKeys)+len(keyvals)/2+len(l.suffixKeys)+extraKeys)
-	prefixFormat := make([]string, 0, len(l.prefixKeys))
-	suffixFormat := make([]string, 0, len(l.suffixKeys))
-	middleFormat := make([]string, 0, len(keyvals)/2) //nolint:mnd // Half.
-	middleKeys := make([]string, 0, len(keyvals)/2)   //nolint:mnd // Half.
-	values := make([]any, 0, len(vals))
-	surroundKeys := make(map[string]bool, len(l.prefixKeys)+len(l.suffixKeys))
+	r := getRecord(l.layout)
+	defer putRecord(r)
 
 	// Gather keys for output:
-	// 1. Add prefixKeys which has non-nil defaultKeyVals.
-	for _, k := range l.prefixKeys {
-		surroundKeys[k] = true
-		if l.defaultKeyvals[k] != nil {
-			vals[k] = l.defaultKeyvals[k]
-		}
-		prefixFormat = append(prefixFormat, l.getFormat(k))
-	}
-	// 2. Add suffixKeys which has non-nil defaultKeyVals.
-	for _, k := range l.suffixKeys {
-		surroundKeys[k] = true
-		if l.defaultKeyvals[k] != nil {
-			vals[k] = l.defaultKeyvals[k]
+	// 1. Add prefixKeys/suffixKeys which has non-nil defaultKeyVals.
+	for k, idx := range l.layout.surround {
+		if v := l.defaultKeyvals[k]; v != nil {
+			r.surround[idx] = surroundVal{val: resolveValue(v), ok: true}
 		}
-		suffixFormat = append(suffixFormat, l.getFormat(k))
 	}
-	// 3. Add msg to middleKeys. Msg value may be nil.
-	middleKeys = append(middleKeys, KeyMessage)
-	middleFormat = append(middleFormat, l.getFormat(KeyMessage))
-	if *l.format == JSON {
-		vals[KeyMessage] = fmt.Sprint(msg) // Avoid marshalling non-string in msg.
-	} else {
-		vals[KeyMessage] = msg
-	}
-	// 4. Add keyvals to prefixKeys/middleKeys/suffixKeys.
+	// 2. Add msg to middle keys. Msg value may be nil.
+	r.set(KeyMessage, msg)
+	// 3. Add keyvals to prefixKeys/middle keys/suffixKeys.
 	//    May overwrite prefixKeys/suffixKeys values from defaultKeyvals.
 	//    May have nil values.
-	seenMiddleKeys := make(map[string]bool, len(middleKeys))
 	for i := 0; i < len(keyvals); i += 2 {
 		k, ok := keyvals[i].(string)
 		if !ok {
 			l.New().AddCallDepth(getPackageDepth()).SetKeyValFormat(" %#[2]v").PrintErr("key is not string", "key", keyvals[i])
 			k = fmt.Sprint(keyvals[i])
 		}
-		if !surroundKeys[k] && !seenMiddleKeys[k] {
-			seenMiddleKeys[k] = true
-			middleKeys = append(middleKeys, k)
-			middleFormat = append(middleFormat, l.getFormat(k))
-		}
 		if t, ok2 := keyvals[i+1].(time.Time); ok2 {
-			vals[k] = t.Format(*l.timeValFormat)
+			r.set(k, t.Format(*l.timeValFormat))
 		} else {
-			vals[k] = keyvals[i+1]
+			r.set(k, keyvals[i+1])
 		}
 	}
-	// 5. Add current time if output format is JSON.
-	if *l.format == JSON {
-		vals[KeyTime] = now().UTC().Format(*l.timeFormat)
-	} else if vals[KeyTime] == Auto {
-		vals[KeyTime] = now().Format(*l.timeFormat)
+	// 4. Add time if user asks for it (JSON and Logfmt will always
+	//    output it unless it's zero).
+	if t == nil {
+		r.time.t = now()
+	} else {
+		r.time.t = *t
 	}
-	// 6. Add log level.
-	vals[KeyLevel] = level
-	// 7. Add unit unless user set it to nil.
+	if v, _ := r.get(KeyTime); v == Auto {
+		if r.time.t.IsZero() {
+			r.unset(KeyTime)
+		} else {
+			r.set(KeyTime, &r.time)
+		}
+	}
+	// 5. Add log level if it's in prefixKeys/suffixKeys or keyvals (JSON
+	//    and Logfmt will always output it).
+	r.level = level
+	r.update(KeyLevel, level)
+	// 6. Add unit unless user set it to nil.
 	//    If user didn't provide custom value then use package name.
-	unit, okUnit := vals[KeyUnit]
-	// 8. Add func and source unless user set them to nil.
-	_, okFunc := vals[KeyFunc]
-	_, okSource := vals[KeySource]
+	unit, okUnit := r.get(KeyUnit)
+	// 7. Add func and source unless user set them to nil.
+	_, okFunc := r.get(KeyFunc)
+	_, okSource := r.get(KeySource)
 	if okUnit && unit == Auto || okSource || okFunc { //nolint:nestif // No idea how to improve.
-		if pc, filePath, line, ok := runtime.Caller(l.callDepth); ok {
+		if pc == 0 {
+			pc = l.callerPC(1)
+		}
+		if funcName, filePath, line, ok := caller(pc); ok {
 			dir, file := path.Split(filePath)
 			if okUnit && unit == Auto {
-				vals[KeyUnit] = path.Base(dir)
+				r.unit.s = path.Base(dir)
+				r.set(KeyUnit, &r.unit)
 			}
 			if okFunc {
-				vals[KeyFunc] = path.Base(runtime.FuncForPC(pc).Name())
+				r.fn.s = path.Base(funcName)
+				r.set(KeyFunc, &r.fn)
 			}
 			if okSource {
-				vals[KeySource] = fmt.Sprintf("%s:%d", file, line)
+				r.source = sourceRef{file: file, line: line}
+				r.set(KeySource, &r.source)
 			}
 		}
 	}
-	// 9. Add stack trace if user asks for it.
+	// 8. Add stack trace if user asks for it.
 	//    If user didn't provide custom value then use default one.
-	stack, okStack := vals[KeyStack]
-	if okStack && stack == Auto {
+	if stack, okStack := r.get(KeyStack); okStack && stack == Auto {
 		const size = 64 << 10
 		buf := make([]byte, size)
-		vals[KeyStack] = string(buf[:runtime.Stack(buf, false)])
+		r.set(KeyStack, string(buf[:runtime.Stack(buf, false)]))
 	}
 
-	// Now we've prepared all middleKeys plus some prefixKeys/suffixKeys
+	// Now we've prepared all middle keys plus some prefixKeys/suffixKeys
 	// which wasn't disabled (nil in defaultKeyvals) and was provided
 	// (non-nil in defaultKeyvals or anything in keyvals) by user.
-	for i, k := range l.prefixKeys {
-		if _, ok := vals[k]; ok {
-			values = append(values, fmt.Sprintf(prefixFormat[i], k, vals[k]))
-		}
+	if len(l.outputs) > 0 {
+		l.writeOutputs(level, pc, keyvals, r)
+		return
 	}
-	for i, k := range middleKeys {
-		values = append(values, fmt.Sprintf(middleFormat[i], k, vals[k]))
+	if s, ok := l.printer.(Sink); ok {
+		s.Handle(r.export())
+		return
 	}
-	for i, k := range l.suffixKeys {
-		if _, ok := vals[k]; ok {
-			values = append(values, fmt.Sprintf(suffixFormat[i], k, vals[k]))
-		}
+	r.buf = l.formatter().appendRecord(r.buf, r)
+	printLine(l.printer, level, r.buf)
+}
+
+// callerPC returns pc of caller l.callDepth frames above l.log.
+//
+// The skip is the number of frames between callerPC's caller and l.log
+// (or other function called directly by public method).
+func (l *Logger) callerPC(skip int) uintptr {
+	const depth = 2 // runtime.Callers plus l.callerPC.
+	var pcs [1]uintptr
+	if runtime.Callers(depth+skip+l.callDepth, pcs[:]) == 0 {
+		return 0
 	}
+	return pcs[0]
+}
 
-	// Output.
-	if *l.format == Text {
-		l.printer.Print(values...)
-	} else {
-		// TODO Split this function into separate ones for Text
-		// and JSON formats, to avoid useless text formatting for JSON.
-		buf, err := json.Marshal(vals)
-		if err != nil {
-			l.printer.Print(err)
-		} else {
-			l.printer.Print(string(buf))
+// unit returns KeyUnit value which will be output for record with
+// keyvals logged at pc. If hasUnit is false then record has no unit.
+//
+// It must be called with l.mu locked.
+func (l *Logger) unit(pc uintptr, keyvals []any) (unit string, hasUnit bool) {
+	v := l.defaultKeyvals[KeyUnit]
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if k, ok := keyvals[i].(string); ok && k == KeyUnit {
+			v = keyvals[i+1]
+		}
+	}
+	switch v := resolveValue(v).(type) {
+	case nil:
+		return "", false
+	case string:
+		if v != Auto {
+			return v, true
 		}
+		_, filePath, _, ok := caller(pc)
+		if !ok {
+			return "", false
+		}
+		dir, _ := path.Split(filePath)
+		return path.Base(dir), true
+	default:
+		return fmt.Sprint(v), true
 	}
 }
 
+// caller returns function name, file and line for pc.
+func caller(pc uintptr) (funcName, filePath string, line int, ok bool) {
+	if pc == 0 {
+		return "", "", 0, false
+	}
+	// pc is a return address, so use pc-1 to get details for the call.
+	fn := runtime.FuncForPC(pc - 1)
+	if fn == nil {
+		return "", "", 0, false
+	}
+	filePath, line = fn.FileLine(pc - 1)
+	return fn.Name(), filePath, line, true
+}
+
 // mergeParent will merge l.parent's settings into l.
 //
 // mergeParent should be called in lazy way before using l settings.
@@ -788,11 +1257,17 @@ func (l *Logger) log(level logLevel, msg any, keyvals ...any) { //nolint:gocyclo
 //	keyValFormat:   use parent only by default
 //	timeFormat:     use parent only by default
 //	timeValFormat:  use parent only by default
+//	typedJSON:      use parent only by default
+//	color:          use parent only by default
+//	messageWidth:   use parent only by default
+//	sanitize:       use parent only by default
 //	callDepth:      add parent's
 //	defaultKeyvals: use parent only by default (set key to nil to drop parent's value)
 //	prefixKeys:     prepend parent's keys (XXX no ease way to replace!)
 //	suffixKeys:     append  parent's keys (XXX no ease way to replace!)
 //	keysFormat:     use parent only by default (set to DefaultKeyValFormat to drop parent's value)
+//	unitLevels:     use parent only by default (set unit to nil to drop parent's value)
+//	outputs:        use parent only by default
 func (l *Logger) mergeParent() {
 	// Handle recursive calls, like in case "key is not string".
 	l.mu.RLock()
@@ -830,6 +1305,18 @@ func (l *Logger) mergeParent() {
 	if l.timeValFormat == nil {
 		l.timeValFormat = p.timeValFormat
 	}
+	if l.typedJSON == nil {
+		l.typedJSON = p.typedJSON
+	}
+	if l.color == nil {
+		l.color = p.color
+	}
+	if l.messageWidth == nil {
+		l.messageWidth = p.messageWidth
+	}
+	if l.sanitize == nil {
+		l.sanitize = p.sanitize
+	}
 	l.callDepth += p.callDepth
 	for k, v := range p.defaultKeyvals {
 		if _, ok := l.defaultKeyvals[k]; !ok {
@@ -843,20 +1330,15 @@ func (l *Logger) mergeParent() {
 			l.keysFormat[k] = v
 		}
 	}
+	if l.outputs == nil {
+		l.outputs = p.outputs
+	}
 
+	l.layout = l.newLayout()
+	l.levels.Store(newLevelVars(l.level, l.unitLevels))
 	l.parent = nil
 }
 
-// getFormat returns keyValFormat for k.
-//
-// mergeParent must be called before getFormat.
-func (l *Logger) getFormat(k string) string {
-	if format, ok := l.keysFormat[k]; ok {
-		return format
-	}
-	return *l.keyValFormat
-}
-
 // getPackageDepth returns current stack depth within caller's package.
 func getPackageDepth() int {
 	_, callerFile, _, ok := runtime.Caller(1)
@@ -874,6 +1356,14 @@ func getPackageDepth() int {
 
 // getErr returns first arg of type error or msg.
 func getErr(msg any, keyvals ...any) error {
+	if err := findErr(msg, keyvals...); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s", msg) //nolint:err113 // By design.
+}
+
+// findErr returns first arg of type error or nil.
+func findErr(msg any, keyvals ...any) error {
 	if err, ok := msg.(error); ok {
 		return err
 	}
@@ -882,5 +1372,5 @@ func getErr(msg any, keyvals ...any) error {
 			return err
 		}
 	}
-	return fmt.Errorf("%s", msg) //nolint:err113 // By design.
+	return nil
 }
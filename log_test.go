@@ -1,8 +1,13 @@
 package structlog_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"io"
+	"log/slog"
 	"sync"
 	"testing"
 
@@ -56,3 +61,237 @@ func TestRace2(_ *testing.T) {
 	close(start)
 	wg.Wait()
 }
+
+func TestLevelLazyInheritance(tt *testing.T) {
+	t := check.T(tt)
+	parent := structlog.New().SetOutput(io.Discard)
+	child1 := parent.New()
+	child2 := parent.New()
+	t.True(child1.IsDebug())
+	parent.SetLogLevel(structlog.WRN)
+	t.True(child1.IsDebug(), "already used, keeps level")
+	t.False(child2.IsInfo(), "not used yet, inherits level")
+	t.False(parent.IsInfo())
+	child1.SetLogLevel(structlog.INF)
+	t.False(child1.IsDebug())
+	t.True(child1.IsInfo())
+	parent.SetLogLevel(structlog.DBG)
+	t.True(parent.IsDebug())
+	t.False(child2.IsInfo())
+}
+
+func TestUnitLevels(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	root := structlog.NewZeroLogger().SetOutput(&buf).SetLogLevel(structlog.INF).
+		SetPrefixKeys(structlog.KeyUnit).SetKeysFormat(map[string]string{structlog.KeyUnit: "%[2]s:"}).
+		SetUnitLevel("db", structlog.DBG).SetUnitLevel("http", structlog.WRN)
+	db := root.New(structlog.KeyUnit, "db")
+	http := root.New(structlog.KeyUnit, "http")
+	auto := root.New().SetUnitLevel(unit, structlog.ERR)
+	noUnit := root.New(structlog.KeyUnit, nil)
+	db.Debug("1")
+	http.Info("2")
+	http.Warn("3")
+	root.Debug("4")
+	root.Info("5")
+	root.Debug("6", structlog.KeyUnit, "db")
+	auto.Warn("7")
+	auto.Err("8")
+	auto.Warn("9", structlog.KeyUnit, "other")
+	noUnit.Info("10")
+	t.True(db.IsDebug())
+	t.False(http.IsInfo())
+	t.False(auto.IsInfo())
+	t.True(root.IsInfo())
+	t.Equal(buf.String(), ""+
+		"db: _m=1\n"+
+		"http: _m=3\n"+
+		unit+": _m=5\n"+
+		"db: _m=6\n"+
+		unit+": _m=8\n"+
+		"other: _m=9\n"+
+		" _m=10\n")
+
+	v := root.UnitLevelVar("http")
+	t.Equal(v.Level(), structlog.WRN)
+	v.Set(structlog.INF)
+	t.True(http.IsInfo())
+	http.SetUnitLevelVar("http", nil)
+	t.Nil(root.New().UnitLevelVar("unknown"))
+}
+
+// Just in case, not sure is it makes any sense to test this.
+func TestRaceLevel(_ *testing.T) {
+	log := structlog.New().SetOutput(io.Discard)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	wg.Go(func() { <-start; log.SetLogLevel(structlog.ERR) })
+	wg.Go(func() { <-start; log.New().Debug("dump") })
+	wg.Go(func() { <-start; _ = log.IsDebug() })
+	close(start)
+	wg.Wait()
+}
+
+func TestLevelValue(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+
+	level, err := structlog.ParseLevelStrict("Warning")
+	t.Nil(err)
+	t.Equal(level, structlog.WRN)
+	_, err = structlog.ParseLevelStrict("verbose")
+	t.Err(err, structlog.ErrUnknownLevel)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&level, "level", "log level")
+	t.Nil(fs.Parse([]string{"-level", "info"}))
+	t.Equal(level, structlog.INF)
+	t.Match(fs.Parse([]string{"-level", "verbose"}), `unknown log level: "verbose"`)
+	t.Equal(level, structlog.INF)
+
+	var cfg struct {
+		Level  structlog.Level  `json:"level"`
+		Format structlog.Format `json:"format"`
+	}
+	t.Nil(json.Unmarshal([]byte(`{"level":"ERR","format":"logfmt"}`), &cfg))
+	t.Equal(cfg.Level, structlog.ERR)
+	t.Equal(cfg.Format, structlog.Logfmt)
+	buf, err := json.Marshal(cfg)
+	t.Nil(err)
+	t.Equal(string(buf), `{"level":"ERR","format":"logfmt"}`)
+	t.Err(json.Unmarshal([]byte(`{"level":"verbose"}`), &cfg), structlog.ErrUnknownLevel)
+	t.Err(json.Unmarshal([]byte(`{"format":"xml"}`), &cfg), structlog.ErrUnknownFormat)
+	t.NotNil(json.Unmarshal([]byte(`{"level":1}`), &cfg))
+
+	text, err := structlog.DBG.MarshalText()
+	t.Nil(err)
+	t.Equal(string(text), "dbg")
+	t.Nil(level.UnmarshalText(text))
+	t.Equal(level, structlog.DBG)
+}
+
+func TestFormatValue(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+
+	format, err := structlog.ParseFormat("JSON")
+	t.Nil(err)
+	t.Equal(format, structlog.JSON)
+	_, err = structlog.ParseFormat("xml")
+	t.Err(err, structlog.ErrUnknownFormat)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&format, "format", "log format")
+	t.Nil(fs.Parse([]string{"-format=text"}))
+	t.Equal(format, structlog.Text)
+	t.Equal(fs.Lookup("format").Value.String(), "text")
+	t.Match(fs.Parse([]string{"-format=xml"}), `unknown log format: "xml"`)
+}
+
+const (
+	NTC structlog.Level = structlog.INF + 2
+	ALR structlog.Level = structlog.CRT + 4
+	EMR structlog.Level = structlog.CRT + 8
+)
+
+func init() { //nolint:gochecknoinits // Recommended way to register levels.
+	structlog.RegisterLevel(NTC, "NTC")
+	structlog.RegisterLevel(ALR, "alert")
+	structlog.RegisterLevel(EMR, "EMERG")
+}
+
+func TestLevels(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	t.PanicMatch(func() { structlog.RegisterLevel(NTC, "NOTE") }, `already registered as "NTC"`)
+	t.PanicMatch(func() { structlog.RegisterLevel(NTC+1, "ntc") }, `"ntc" is already used`)
+	t.PanicMatch(func() { structlog.RegisterLevel(NTC+1, "Trace") }, `"Trace" is already used`)
+	t.PanicMatch(func() { structlog.RegisterLevel(NTC+1, "Alert") }, `"Alert" is already used`)
+
+	var buf bytes.Buffer
+	log := structlog.NewZeroLogger().SetOutput(&buf).SetPrefixKeys(structlog.KeyLevel).SetLogLevel(structlog.TRC)
+	log.Trace("1")
+	t.Err(log.Crit("2", "err", io.EOF), io.EOF)
+	log.Log(NTC, "3")
+	log.Log(NTC+1, "4")
+	slog.New(structlog.NewSlogHandler(log)).Log(context.Background(), slog.LevelInfo+3, "5")
+	slog.New(structlog.NewSlogHandler(log)).Log(context.Background(), slog.LevelDebug-10, "6")
+	t.Equal(buf.String(), ""+
+		" _l=trc _m=1\n"+
+		" _l=CRT _m=2 err=EOF\n"+
+		" _l=NTC _m=3\n"+
+		" _l=??? _m=4\n"+
+		" _l=NTC _m=5\n"+
+		" _l=trc _m=6\n")
+
+	t.True(log.IsTrace())
+	log.SetLogLevel(structlog.DBG)
+	t.False(log.IsTrace())
+	t.True(log.IsDebug())
+	log.SetLogLevel(structlog.ParseLevel("ntc"))
+	t.False(log.IsInfo())
+	t.True(log.New().LevelVar().Level() == NTC)
+
+	for name, want := range map[string]structlog.Level{
+		"trace": structlog.TRC, "fatal": structlog.CRT, "CRT": structlog.CRT, "Ntc": NTC,
+		"notice": structlog.INF, "alert": ALR, "emerg": EMR, "emergency": structlog.CRT,
+	} {
+		level, err := structlog.ParseLevelStrict(name)
+		t.Nil(err)
+		t.Equal(level, want)
+	}
+	buf.Reset()
+	t.Nil(json.NewEncoder(&buf).Encode([]structlog.Level{structlog.TRC, structlog.CRT, NTC}))
+	t.Equal(buf.String(), `["trc","CRT","NTC"]`+"\n")
+}
+
+func TestSanitize(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := structlog.New(structlog.KeyApp, "app", structlog.KeyPID, 1, structlog.KeyFunc, nil, structlog.KeySource, nil).
+		SetOutput(&buf).SetKeysFormat(map[string]string{"raw": " %s=%-5v|"})
+	log.Info("multi\nline", "k\n", "v\r\n\x1b[31m", "raw", "a\tb\n", structlog.KeyStack, "stack\n\ttrace")
+	log.SetSanitize(true)
+	log.Info("multi\nline", "k\n", "v\r\n\x1b[31m", "raw", "a\tb\n", structlog.KeyStack, "stack\n\ttrace")
+	log.Info(`back\slash`, "k", "a\\b\x9b")
+	log.Info("back\\slash\ttab")
+	t.Equal(buf.String(), ""+
+		"app[1] inf "+unit+": \"multi\\nline\" k\n=v\r\n\x1b[31m raw=a\tb\n |\nstack\n\ttrace\n"+
+		"app[1] inf "+unit+": \"multi\\nline\" k\\n=v\\r\\n\\x1b[31m raw=a\tb\\n |\nstack\n\ttrace\n"+
+		"app[1] inf "+unit+": `back\\slash` k=a\\\\b\\x9b\n"+
+		"app[1] inf "+unit+": \"back\\\\slash\\ttab\"\n")
+}
+
+func TestUnitLevelsAfterUse(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	root := structlog.NewZeroLogger().SetOutput(&buf).SetLogLevel(structlog.INF).
+		SetPrefixKeys(structlog.KeyUnit).SetKeysFormat(map[string]string{structlog.KeyUnit: "%[2]s:"})
+	db := root.New(structlog.KeyUnit, "db")
+	own := root.New(structlog.KeyUnit, "db").SetUnitLevel("db", structlog.ERR)
+	db.Debug("1")
+	own.Debug("2")
+	t.False(db.IsDebug())
+
+	root.SetUnitLevel("db", structlog.DBG)
+	db.Debug("3")
+	own.Debug("4")
+	own.Err("5")
+	t.True(db.IsDebug())
+	t.Equal(db.UnitLevelVar("db").Level(), structlog.DBG)
+	t.Equal(*db.Snapshot().Level, structlog.INF)
+	t.DeepEqual(db.Snapshot().UnitLevels, map[string]structlog.Level{"db": structlog.DBG})
+
+	root.SetUnitLevelVar("db", nil)
+	db.Debug("6")
+	t.Nil(db.UnitLevelVar("db"))
+	t.Equal(buf.String(), ""+
+		"db: _m=3\n"+
+		"db: _m=5\n")
+}
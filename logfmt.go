@@ -0,0 +1,67 @@
+package structlog
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// appendLogfmtKey appends k with all chars not allowed in logfmt key
+// replaced by '_'. Empty key will be output as '_'.
+func appendLogfmtKey(buf []byte, k string) []byte {
+	if k == "" {
+		return append(buf, '_')
+	}
+	for _, r := range k {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			r = '_'
+		}
+		buf = utf8.AppendRune(buf, r)
+	}
+	return buf
+}
+
+// appendLogfmtValue appends v, quoted if it's empty or contains chars
+// not allowed in unquoted logfmt value.
+func appendLogfmtValue(buf, v []byte) []byte {
+	if !needsLogfmtQuote(v) {
+		return append(buf, v...)
+	}
+	buf = append(buf, '"')
+	for i := 0; i < len(v); {
+		r, size := utf8.DecodeRune(v[i:])
+		switch {
+		case r == '"' || r == '\\':
+			buf = append(buf, '\\', byte(r))
+		case r == '\n':
+			buf = append(buf, `\n`...)
+		case r == '\r':
+			buf = append(buf, `\r`...)
+		case r == '\t':
+			buf = append(buf, `\t`...)
+		case r == utf8.RuneError && size == 1:
+			buf = append(buf, "\ufffd"...)
+		case r < ' ' || r == 0x7f || !unicode.IsPrint(r) && !unicode.IsSpace(r):
+			buf = fmt.Appendf(buf, `\u%04x`, r)
+		default:
+			buf = append(buf, v[i:i+size]...)
+		}
+		i += size
+	}
+	return append(buf, '"')
+}
+
+func needsLogfmtQuote(v []byte) bool {
+	if len(v) == 0 {
+		return true
+	}
+	for i := 0; i < len(v); {
+		r, size := utf8.DecodeRune(v[i:])
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f ||
+			r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package structlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestLogfmt(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := structlog.New().SetOutput(&buf).SetLogFormat(structlog.Logfmt)
+	log.Info("some message", "a", 1, "b", "", "c", `say "hi"`, "d", "k=v", "e", "two\nlines", "bad key", `\`, "f", "\x1b[31m", "g", "ok")
+	t.Equal(buf.String(), ``+
+		`_t="Jan  2 02:04:05.123456" _a=structlog.test _p=`+pid+` _l=inf _u=`+unit+` `+
+		`_m="some message" a=1 b="" c="say \"hi\"" d="k=v" e="two\nlines" bad_key="\\" f="\u001b[31m" g=ok `+
+		`_f=structlog_test.TestLogfmt _s=logfmt_test.go:17`+"\n")
+
+	buf.Reset()
+	structlog.NewZeroLogger().SetOutput(&buf).SetLogFormat(structlog.Logfmt).
+		SetPrefixKeys(structlog.KeyLevel).SetSuffixKeys(structlog.KeyTime).
+		SetTimeFormat("15:04:05").
+		Err("ü", "nil", nil)
+	t.Equal(buf.String(), `_l=ERR _m=ü nil=<nil> _t=02:04:05`+"\n")
+}
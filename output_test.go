@@ -50,3 +50,29 @@ func TestOutput(tt *testing.T) {
 		"structlog.test["+pid+"] inf "+unit+": `something happens` k1=v1 k2=v2 \t@ structlog_test.TestOutput(output_test.go:47)\n"+
 		"structlog.test["+pid+"] WRN "+unit+": `oops` \t@ structlog_test.TestOutput(output_test.go:48)\n")
 }
+
+func TestDefaultPrinterColorAuto(tt *testing.T) {
+	t := check.T(tt)
+	t.Setenv("NO_COLOR", "")
+	defer stdlog.SetOutput(os.Stderr)
+	rec := &structlog.Record{Level: structlog.WRN, Keyvals: []any{structlog.KeyLevel, structlog.WRN}}
+	format := func() string {
+		return string(structlog.NewZeroLogger().SetPrefixKeys(structlog.KeyLevel).Formatter().AppendRecord(nil, rec))
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	t.Nil(err)
+	defer devNull.Close()
+	stdlog.SetOutput(devNull)
+	t.Equal(format(), " _l=WRN")
+
+	tty, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skip("no pseudo-terminal: ", err)
+	}
+	defer tty.Close()
+	stdlog.SetOutput(tty)
+	t.Equal(format(), " _l=\x1b[33mWRN\x1b[0m")
+	stdlog.SetOutput(&bytes.Buffer{})
+	t.Equal(format(), " _l=WRN")
+}
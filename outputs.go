@@ -0,0 +1,80 @@
+package structlog
+
+// SetOutputs makes l send log records to given outputs instead of l's
+// own Printer. Each output is a Logger which provides output settings:
+// Printer, format (SetLogFormat, SetPrefixKeys, SetKeysFormat,
+// SetTimeFormat, etc.) and levels (SetLogLevel, SetUnitLevel). Other
+// settings of outputs (like default keyvals or own outputs) are not used.
+//
+// Log record will be sent to outputs only if it's enabled by both l's
+// level and output's level, so l's level should not be higher than
+// lowest level of outputs.
+//
+// Use SetOutputs without arguments to make l use own Printer instead of
+// outputs inherited from parent logger.
+//
+// Example: output Text at DBG level to stderr and JSON at INF level to
+// a file:
+//
+//	structlog.DefaultLogger.SetLogLevel(structlog.DBG).SetOutputs(
+//		structlog.DefaultLogger.New().SetOutput(os.Stderr),
+//		structlog.DefaultLogger.New().SetOutput(f).
+//			SetLogFormat(structlog.JSON).SetLogLevel(structlog.INF),
+//	)
+//
+// It doesn't creates a new logger, it returns l just for convenience.
+func (l *Logger) SetOutputs(outputs ...*Logger) *Logger {
+	for _, out := range outputs {
+		if out == nil {
+			panic("SetOutputs called with nil *Logger")
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.outputs = append(make([]*Logger, 0, len(outputs)), outputs...)
+	return l
+}
+
+// writeOutputs sends r to l.outputs.
+//
+// If pc is zero then it'll be calculated using l.callDepth, which
+// assumes writeOutputs is called by l.output.
+func (l *Logger) writeOutputs(level Level, pc uintptr, keyvals []any, r *logRecord) {
+	var unit string
+	hasUnit, unitDone := false, false
+	for _, out := range l.outputs {
+		if !out.enabled(level) { // Also calls mergeParent.
+			continue
+		}
+		if lv := out.levels.Load(); !lv.units.isEmpty() {
+			if !unitDone {
+				if pc == 0 {
+					pc = l.callerPC(2) //nolint:mnd // Skip l.writeOutputs and l.output.
+				}
+				unit, hasUnit = l.unit(pc, keyvals)
+				unitDone = true
+			}
+			if level < lv.unitLevel(unit, hasUnit) {
+				continue
+			}
+		}
+		out.write(r)
+	}
+}
+
+// write outputs r using l's Printer and format.
+func (l *Logger) write(r *logRecord) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if s, ok := l.printer.(Sink); ok {
+		s.Handle(r.export())
+		return
+	}
+	if l.layout == r.lay {
+		r.buf = l.formatter().appendRecord(r.buf[:0], r)
+	} else {
+		r.buf = recordFormatter{f: l.formatter()}.AppendRecord(r.buf[:0], r.export())
+	}
+	printLine(l.printer, r.level, r.buf)
+}
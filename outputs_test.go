@@ -0,0 +1,45 @@
+package structlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestOutputs(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var text, json bytes.Buffer
+	var recs []string
+	root := structlog.New().SetLogLevel(structlog.DBG).SetOutputs(
+		structlog.New().SetOutput(&text).SetDefaultKeyvals("ignored", 1),
+		structlog.NewZeroLogger().SetOutput(&json).
+			SetLogFormat(structlog.JSON).SetTimeFormat("15:04").
+			SetLogLevel(structlog.INF).SetUnitLevel("db", structlog.DBG),
+		structlog.NewZeroLogger().SetLogLevel(structlog.ERR).
+			SetSink(structlog.SinkFunc(func(r *structlog.Record) { recs = append(recs, r.Message.(string)) })),
+	)
+	log := root.New()
+	log.Debug("dbg")
+	log.Info("inf")
+	log.Debug("query", structlog.KeyUnit, "db")
+	log.Err("failed")
+	log.New().SetOutput(&text).Warn("own output")
+
+	t.Equal(text.String(), ""+
+		"structlog.test["+pid+"] dbg "+unit+": `dbg` \t@ structlog_test.TestOutputs(outputs_test.go:26)\n"+
+		"structlog.test["+pid+"] inf "+unit+": `inf` \t@ structlog_test.TestOutputs(outputs_test.go:27)\n"+
+		"structlog.test["+pid+"] dbg db: `query` \t@ structlog_test.TestOutputs(outputs_test.go:28)\n"+
+		"structlog.test["+pid+"] ERR "+unit+": `failed` \t@ structlog_test.TestOutputs(outputs_test.go:29)\n"+
+		"structlog.test["+pid+"] WRN "+unit+": `own output` \t@ structlog_test.TestOutputs(outputs_test.go:30)\n")
+	t.Equal(json.String(), ""+
+		`{"_t":"02:04","_l":"inf","_a":"structlog.test","_p":"`+pid+`","_u":"`+unit+`","_m":"inf","_f":"structlog_test.TestOutputs","_s":"outputs_test.go:27"}`+"\n"+
+		`{"_t":"02:04","_l":"dbg","_a":"structlog.test","_p":"`+pid+`","_u":"db","_m":"query","_f":"structlog_test.TestOutputs","_s":"outputs_test.go:28"}`+"\n"+
+		`{"_t":"02:04","_l":"ERR","_a":"structlog.test","_p":"`+pid+`","_u":"`+unit+`","_m":"failed","_f":"structlog_test.TestOutputs","_s":"outputs_test.go:29"}`+"\n")
+	t.DeepEqual(recs, []string{"failed"})
+
+	t.PanicMatch(func() { structlog.New().SetOutputs(nil) }, "nil")
+}
@@ -0,0 +1,358 @@
+package structlog
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrParseText is returned by ParseText if line doesn't match layout.
+var ErrParseText = errors.New("line doesn't match Text layout")
+
+// ParseText parses log line output in Text format back into Record.
+//
+// Layout is defined by cfg's PrefixKeys, SuffixKeys, KeysFormat,
+// KeyValFormat and TimeFormat (empty KeyValFormat and TimeFormat means
+// defaults), so to parse lines output by DefaultLogger use:
+//
+//	rec, err := structlog.ParseText(line, structlog.DefaultLogger.Snapshot())
+//
+// Record.Keyvals will contain keys in same order as in line. Values are
+// strings (unquoted if output using %q) except KeyTime (time.Time in
+// local time zone, with current year if TimeFormat has no year),
+// KeyLevel (Level, if it's a known level) and values output using %d
+// (int). KeyTime and values output using %d are matched only if they
+// can be parsed.
+//
+// Text format is ambiguous, so ParseText uses some heuristics: value
+// (except KeyStack) can't contain newline, value without quotes ends
+// before anything which looks like a next key, etc. Key formats must
+// contain only %s, %v, %d and %q verbs (like used by DefaultLogger) and
+// two verbs must be separated by some text.
+func ParseText(line string, cfg Config) (*Record, error) {
+	p, err := newTextParser(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.parse(strings.TrimSuffix(line, "\n"))
+}
+
+type parseKey struct {
+	key    string
+	format *keyFormat
+}
+
+// textParser is a state of ParseText.
+type textParser struct {
+	timeFormat string
+	timeLen    int // Length of time formatted using timeFormat, if it's constant.
+	keyVal     *keyFormat
+	prefix     []parseKey
+	suffix     []parseKey
+	middle     []parseKey // Keys with custom format not in prefix/suffix.
+	leading    []string   // Leading literals of all formats.
+	line       string
+	keyvals    []any
+	isSuffix   []int8 // Cached isSuffixStart results by pos: 0 - unknown, 1 - false, 2 - true.
+}
+
+func newTextParser(cfg Config) (*textParser, error) {
+	p := &textParser{timeFormat: cfg.TimeFormat}
+	if p.timeFormat == "" {
+		p.timeFormat = DefaultTimeFormat
+	}
+	short := time.Date(2006, 1, 2, 3, 4, 5, 0, time.UTC).Format(p.timeFormat)
+	long := time.Date(2020, 12, 31, 23, 59, 59, 999999999, time.UTC).Format(p.timeFormat)
+	if len(short) == len(long) {
+		p.timeLen = len(short)
+	}
+	keyValFormat := cfg.KeyValFormat
+	if keyValFormat == "" {
+		keyValFormat = DefaultKeyValFormat
+	}
+	var err error
+	compile := func(key, format string) *keyFormat {
+		kf := compileFormat(format)
+		if kf.parts == nil && err == nil {
+			err = fmt.Errorf("%w: key %q has unsupported format %q", ErrParseText, key, format)
+		}
+		if len(kf.parts) > 0 && kf.parts[0].arg == 0 {
+			p.leading = append(p.leading, kf.parts[0].lit)
+		}
+		return kf
+	}
+	p.keyVal = compile("", keyValFormat)
+	surround := make(map[string]bool)
+	keys := func(keys []string) []parseKey {
+		pks := make([]parseKey, 0, len(keys))
+		for _, k := range keys {
+			if surround[k] {
+				continue
+			}
+			surround[k] = true
+			kf := p.keyVal
+			if format, ok := cfg.KeysFormat[k]; ok {
+				kf = compile(k, format)
+			}
+			pks = append(pks, parseKey{key: k, format: kf})
+		}
+		return pks
+	}
+	p.prefix = keys(cfg.PrefixKeys)
+	p.suffix = keys(cfg.SuffixKeys)
+	for _, k := range slices.Sorted(maps.Keys(cfg.KeysFormat)) {
+		if surround[k] {
+			continue
+		}
+		pk := parseKey{key: k, format: compile(k, cfg.KeysFormat[k])}
+		if k == KeyMessage { // Usually first.
+			p.middle = slices.Insert(p.middle, 0, pk)
+		} else {
+			p.middle = append(p.middle, pk)
+		}
+	}
+	return p, err
+}
+
+func (p *textParser) parse(line string) (*Record, error) {
+	p.line = line
+	p.isSuffix = make([]int8, len(line)+1)
+	pos := 0
+	for _, pk := range p.prefix {
+		p.match(pk.format, pk.key, pos, p.isNext, false, func(k string, v any, end int) bool {
+			p.keyvals = append(p.keyvals, k, v)
+			pos = end
+			return true
+		})
+	}
+	for !p.parseSuffix(0, pos) {
+		if !p.parseMiddle(&pos) {
+			return nil, fmt.Errorf("%w: at %d: %q", ErrParseText, pos, line)
+		}
+	}
+	return p.record(), nil
+}
+
+// parseMiddle parses key/value at *pos which is not a prefix/suffix key.
+func (p *textParser) parseMiddle(pos *int) bool {
+	stop := func(end int) bool { return p.isKeyStart(end) || p.isSuffixStart(end) }
+	cont := func(k string, v any, end int) bool {
+		if end == *pos {
+			return false
+		}
+		p.keyvals = append(p.keyvals, k, v)
+		*pos = end
+		return true
+	}
+	for _, pk := range p.middle {
+		if p.match(pk.format, pk.key, *pos, stop, false, cont) {
+			return true
+		}
+	}
+	return p.match(p.keyVal, "", *pos, stop, false, cont)
+}
+
+// parseSuffix parses suffix keys starting from p.suffix[i] at pos till
+// end of line.
+func (p *textParser) parseSuffix(i, pos int) bool {
+	if pos == len(p.line) {
+		return true
+	}
+	if i == len(p.suffix) {
+		return false
+	}
+	n := len(p.keyvals)
+	stop := func(end int) bool { return slices.ContainsFunc(p.suffix[i+1:], p.startsWith(end)) }
+	if p.match(p.suffix[i].format, p.suffix[i].key, pos, stop, true, func(k string, v any, end int) bool {
+		p.keyvals = append(p.keyvals[:n], k, v)
+		return p.parseSuffix(i+1, end)
+	}) {
+		return true
+	}
+	p.keyvals = p.keyvals[:n]
+	return p.parseSuffix(i+1, pos)
+}
+
+// isSuffixStart reports is suffix keys match at pos till end of line.
+// Result depends only on pos, so it's cached to avoid quadratic time.
+func (p *textParser) isSuffixStart(pos int) bool {
+	if p.isSuffix[pos] == 0 {
+		n := len(p.keyvals)
+		p.isSuffix[pos] = 1
+		if p.parseSuffix(0, pos) {
+			p.isSuffix[pos] = 2
+		}
+		p.keyvals = p.keyvals[:n]
+	}
+	return p.isSuffix[pos] == 2
+}
+
+// isNext reports is some key format's leading literal matches at pos.
+func (p *textParser) isNext(pos int) bool {
+	for _, lit := range p.leading {
+		if strings.HasPrefix(p.line[pos:], lit) {
+			return true
+		}
+	}
+	return false
+}
+
+// startsWith returns function which reports is key format's leading
+// literal matches at pos.
+func (p *textParser) startsWith(pos int) func(parseKey) bool {
+	return func(pk parseKey) bool {
+		parts := pk.format.parts
+		return len(parts) > 0 && parts[0].arg == 0 && strings.HasPrefix(p.line[pos:], parts[0].lit)
+	}
+}
+
+// isKeyStart reports is KeyValFormat matches at pos up to the end of key
+// (which can't contain spaces).
+func (p *textParser) isKeyStart(pos int) bool {
+	s, parts := p.line[pos:], p.keyVal.parts
+	for i, part := range parts {
+		switch {
+		case part.arg == 0:
+			if !strings.HasPrefix(s, part.lit) {
+				return false
+			}
+			s = s[len(part.lit):]
+		case part.arg == 1 && part.verb == 'q':
+			_, err := strconv.QuotedPrefix(s)
+			return err == nil
+		case part.arg == 1 && i+1 < len(parts):
+			lit := parts[i+1].lit
+			if end := strings.IndexAny(s, " \n"); end >= 0 { // Avoid scanning whole line.
+				s = s[:min(len(s), end+len(lit))]
+			}
+			n := strings.Index(s, lit)
+			return n > 0 && !strings.ContainsAny(s[:n], " \n")
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// match matches kf at pos. For each possible way to match it calls cont
+// with matched key (or key if kf doesn't contain key), value and end
+// position until cont returns true. Value output at end of kf may end
+// only where stop returns true or before newline (except KeyStack) or
+// at end of line; these ends are tried from shortest to longest (or in
+// reverse order if longest is true) with line end or newline last.
+func (p *textParser) match(kf *keyFormat, key string, pos int, stop func(int) bool, longest bool, cont func(k string, v any, end int) bool) bool {
+	parts := kf.parts
+	k, v := key, any(nil)
+	set := func(part *formatPart, s string) bool {
+		switch {
+		case part.arg == 1:
+			k = s
+		case key == KeyTime:
+			t, ok := p.parseTime(s)
+			v = t
+			return ok
+		case part.verb == 'd':
+			i, err := strconv.Atoi(s)
+			v = i
+			return err == nil
+		default:
+			v = s
+		}
+		return true
+	}
+	var step func(i, pos int) bool
+	step = func(i, pos int) bool {
+		if i == len(parts) {
+			return cont(k, v, pos)
+		}
+		part, s := &parts[i], p.line[pos:]
+		switch {
+		case part.arg == 0:
+			return strings.HasPrefix(s, part.lit) && step(i+1, pos+len(part.lit))
+		case part.verb == 'q':
+			q, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return false
+			}
+			u, _ := strconv.Unquote(q)
+			return set(part, u) && step(i+1, pos+len(q))
+		}
+		if part.arg == 2 && key == KeyTime && p.timeLen > 0 && p.timeLen <= len(s) &&
+			set(part, s[:p.timeLen]) && step(i+1, pos+p.timeLen) {
+			return true
+		}
+		if i+1 < len(parts) {
+			n := strings.Index(s, parts[i+1].lit)
+			if parts[i+1].arg != 0 || n < 0 || n == 0 && part.arg == 1 {
+				return false
+			}
+			return set(part, s[:n]) && step(i+1, pos+n)
+		}
+		limit := len(p.line)
+		if n := strings.IndexByte(s, '\n'); n >= 0 && key != KeyStack {
+			limit = pos + n
+		}
+		try := func(end int) bool { return stop(end) && set(part, p.line[pos:end]) && step(i+1, end) }
+		if longest {
+			for end := limit - 1; end >= pos; end-- {
+				if try(end) {
+					return true
+				}
+			}
+		} else {
+			for end := pos; end < limit; end++ {
+				if try(end) {
+					return true
+				}
+			}
+		}
+		return set(part, p.line[pos:limit]) && step(i+1, limit)
+	}
+	return step(0, pos)
+}
+
+func (p *textParser) parseTime(s string) (time.Time, bool) {
+	t, err := time.ParseInLocation(p.timeFormat, s, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if t.Year() == 0 {
+		t = t.AddDate(time.Now().Year(), 0, 0)
+	}
+	return t, true
+}
+
+// record returns Record for parsed p.keyvals.
+func (p *textParser) record() *Record {
+	rec := &Record{Level: INF, Keyvals: p.keyvals}
+	for i := 0; i+1 < len(rec.Keyvals); i += 2 {
+		k, _ := rec.Keyvals[i].(string)
+		v := rec.Keyvals[i+1]
+		switch k {
+		case KeyTime:
+			rec.Time, _ = v.(time.Time)
+		case KeyLevel:
+			if s, ok := v.(string); ok {
+				if level, err := ParseLevelStrict(s); err == nil {
+					rec.Level, v = level, level
+				}
+			}
+		case KeyMessage:
+			rec.Message = v
+		case KeyUnit:
+			rec.Unit = fmt.Sprint(v)
+		case KeyFunc:
+			rec.Func = fmt.Sprint(v)
+		case KeySource:
+			rec.Source = fmt.Sprint(v)
+		case KeyStack:
+			rec.Stack = fmt.Sprint(v)
+		}
+		rec.Keyvals[i+1] = v
+	}
+	return rec
+}
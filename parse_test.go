@@ -0,0 +1,103 @@
+package structlog_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestParseText(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := structlog.New(structlog.KeyTime, structlog.Auto).SetOutput(&buf)
+	log.Info("msg with `quote`", "k1", "v1", "k2", "v 2", "n", 42)
+	log.Err("oops\n", "err", "EOF", structlog.KeyStack, "goroutine 1 [running]:\nmain.(*T).f(a.go:1)")
+	lines := strings.SplitN(buf.String(), "\n", 2)
+
+	cfg := structlog.DefaultLogger.Snapshot()
+	rec, err := structlog.ParseText(lines[0], cfg)
+	t.Nil(err)
+	t.Equal(rec.Time.Format(time.StampMicro), "Jan  2 03:04:05.123456")
+	rec.Time, rec.Keyvals[1] = time.Time{}, nil
+	t.DeepEqual(rec, &structlog.Record{
+		Level:   structlog.INF,
+		Message: "msg with `quote`",
+		Unit:    unit,
+		Func:    "structlog_test.TestParseText",
+		Source:  "parse_test.go:20",
+		Keyvals: []any{
+			structlog.KeyTime, nil,
+			structlog.KeyApp, "structlog.test",
+			structlog.KeyPID, os.Getpid(),
+			structlog.KeyLevel, structlog.INF,
+			structlog.KeyUnit, unit,
+			structlog.KeyMessage, "msg with `quote`",
+			"k1", "v1",
+			"k2", "v 2",
+			"n", "42",
+			structlog.KeyFunc, "structlog_test.TestParseText",
+			structlog.KeySource, "parse_test.go:20",
+		},
+	})
+
+	rec, err = structlog.ParseText(lines[1], cfg)
+	t.Nil(err)
+	t.Equal(rec.Level, structlog.ERR)
+	t.Equal(rec.Message, "oops\n")
+	t.Equal(rec.Source, "parse_test.go:21")
+	t.Equal(rec.Stack, "goroutine 1 [running]:\nmain.(*T).f(a.go:1)")
+	t.DeepEqual(rec.Keyvals[12:14], []any{"err", "EOF"})
+}
+
+func TestParseTextLayout(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	cfg := structlog.Config{
+		TimeFormat: time.DateTime,
+		PrefixKeys: []string{structlog.KeyTime, structlog.KeyLevel},
+		SuffixKeys: []string{structlog.KeyFunc},
+		KeysFormat: map[string]string{
+			structlog.KeyTime:    "%[2]s",
+			structlog.KeyLevel:   " [%[2]s]",
+			structlog.KeyMessage: " %[2]s;",
+			structlog.KeyFunc:    " at %[2]s",
+			"quoted":             " %[1]s=%[2]q",
+		},
+		KeyValFormat: " %s: %v",
+	}
+	rec, err := structlog.ParseText("2020-01-02 03:04:05 [WRN] message here; quoted=\"a b\" k: v at main.f\n", cfg)
+	t.Nil(err)
+	t.Equal(rec.Time, time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local))
+	t.DeepEqual(rec.Keyvals[2:], []any{
+		structlog.KeyLevel, structlog.WRN,
+		structlog.KeyMessage, "message here",
+		"quoted", "a b",
+		"k", "v",
+		structlog.KeyFunc, "main.f",
+	})
+
+	rec, err = structlog.ParseText("bad time [inf] msg;", cfg)
+	t.Err(err, structlog.ErrParseText)
+	t.Nil(rec)
+}
+
+func TestParseTextErrors(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	cfg := structlog.DefaultLogger.Snapshot()
+	_, err := structlog.ParseText("app[1] inf u: not quoted", cfg)
+	t.Err(err, structlog.ErrParseText)
+	t.Match(err, `at 13:`)
+
+	cfg.KeysFormat = map[string]string{structlog.KeyMessage: "%-20[2]s"}
+	_, err = structlog.ParseText("msg", cfg)
+	t.Err(err, structlog.ErrParseText)
+	t.Match(err, `unsupported format`)
+}
@@ -0,0 +1,425 @@
+package structlog
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// layout contains settings required to output a log record which are
+// pre-calculated when l's keys or formats changes.
+type layout struct {
+	surround     map[string]int // Index in logRecord.surround for prefixKeys/suffixKeys.
+	prefix       []layoutKey
+	suffix       []layoutKey
+	keysFormat   map[string]*keyFormat
+	keyValFormat *keyFormat
+}
+
+type layoutKey struct {
+	key    string
+	idx    int // Index in logRecord.surround.
+	format *keyFormat
+}
+
+// newLayout returns layout for l.
+//
+// mergeParent must be called before newLayout.
+func (l *Logger) newLayout() *layout {
+	lay := &layout{
+		surround:     make(map[string]int, len(l.prefixKeys)+len(l.suffixKeys)),
+		prefix:       make([]layoutKey, len(l.prefixKeys)),
+		suffix:       make([]layoutKey, len(l.suffixKeys)),
+		keysFormat:   make(map[string]*keyFormat, len(l.keysFormat)),
+		keyValFormat: compileFormat(*l.keyValFormat),
+	}
+	for k, format := range l.keysFormat {
+		lay.keysFormat[k] = compileFormat(format)
+	}
+	for i, k := range l.prefixKeys {
+		lay.prefix[i] = lay.newLayoutKey(k)
+	}
+	for i, k := range l.suffixKeys {
+		lay.suffix[i] = lay.newLayoutKey(k)
+	}
+	return lay
+}
+
+func (lay *layout) newLayoutKey(k string) layoutKey {
+	idx, ok := lay.surround[k]
+	if !ok {
+		idx = len(lay.surround)
+		lay.surround[k] = idx
+	}
+	return layoutKey{key: k, idx: idx, format: lay.format(k)}
+}
+
+// format returns compiled keyValFormat for k.
+func (lay *layout) format(k string) *keyFormat {
+	if kf, ok := lay.keysFormat[k]; ok {
+		return kf
+	}
+	return lay.keyValFormat
+}
+
+// logRecord contains all data required to output a single log record.
+// It's reused using recordPool to avoid allocations.
+type logRecord struct {
+	lay      *layout
+	surround []surroundVal
+	middle   []middleVal
+	emitted  []bool
+	buf      []byte
+	scratch  []byte
+	unit     strRef
+	fn       strRef
+	source   sourceRef
+	time     timeRef // Used as KeyTime value in Text format.
+	utc      timeRef // Used as KeyTime value in JSON and Logfmt formats.
+	level    Level
+	rec      Record // Used by export.
+}
+
+type surroundVal struct {
+	val any
+	ok  bool
+}
+
+type middleVal struct {
+	key string
+	val any
+}
+
+// Records with larger buffers (e.g. with stack) won't be reused.
+const maxPooledBufSize = 16 << 10
+
+var recordPool = sync.Pool{ //nolint:gochecknoglobals // Pool.
+	New: func() any { return new(logRecord) },
+}
+
+// getRecord returns empty record for lay.
+func getRecord(lay *layout) *logRecord {
+	r := recordPool.Get().(*logRecord) //nolint:forcetypeassert,errcheck // Always *logRecord.
+	r.lay = lay
+	if cap(r.surround) < len(lay.surround) {
+		r.surround = make([]surroundVal, len(lay.surround))
+	} else {
+		r.surround = r.surround[:len(lay.surround)]
+		clear(r.surround)
+	}
+	return r
+}
+
+// putRecord returns r to recordPool.
+func putRecord(r *logRecord) {
+	if cap(r.buf) > maxPooledBufSize || cap(r.scratch) > maxPooledBufSize {
+		return
+	}
+	clear(r.middle)
+	clear(r.surround)
+	clear(r.rec.Keyvals)
+	*r = logRecord{
+		surround: r.surround[:0],
+		middle:   r.middle[:0],
+		emitted:  r.emitted[:0],
+		buf:      r.buf[:0],
+		scratch:  r.scratch[:0],
+		rec:      Record{Keyvals: r.rec.Keyvals[:0]},
+	}
+	recordPool.Put(r)
+}
+
+// get returns value for key k.
+func (r *logRecord) get(k string) (any, bool) {
+	if idx, ok := r.lay.surround[k]; ok && r.surround[idx].ok {
+		return r.surround[idx].val, true
+	}
+	for i := range r.middle {
+		if r.middle[i].key == k {
+			return r.middle[i].val, true
+		}
+	}
+	return nil, false
+}
+
+// set adds/replaces value for key k. Keys which are not in
+// prefixKeys/suffixKeys will be output after already added ones.
+func (r *logRecord) set(k string, v any) {
+	idx, isSurround := r.lay.surround[k]
+	if isSurround {
+		r.surround[idx] = surroundVal{val: v, ok: true}
+	}
+	for i := range r.middle {
+		if r.middle[i].key == k {
+			r.middle[i].val = v
+			return
+		}
+	}
+	if !isSurround {
+		r.middle = append(r.middle, middleVal{key: k, val: v})
+	}
+}
+
+// unset removes value for key k.
+func (r *logRecord) unset(k string) {
+	if idx, ok := r.lay.surround[k]; ok {
+		r.surround[idx] = surroundVal{}
+	}
+	r.middle = slices.DeleteFunc(r.middle, func(m middleVal) bool { return m.key == k })
+}
+
+// update replaces value for key k if it's in prefixKeys/suffixKeys or
+// was already added.
+func (r *logRecord) update(k string, v any) {
+	if _, ok := r.lay.surround[k]; ok {
+		r.set(k, v)
+		return
+	}
+	for i := range r.middle {
+		if r.middle[i].key == k {
+			r.middle[i].val = v
+			return
+		}
+	}
+}
+
+// strRef is a string stored inside logRecord, it's used to avoid
+// allocation on conversion of string to interface.
+type strRef struct{ s string }
+
+func (v *strRef) String() string { return v.s }
+
+// sourceRef is a caller's file and line stored inside logRecord.
+type sourceRef struct {
+	file string
+	line int
+}
+
+func (v *sourceRef) String() string { return string(v.appendTo(nil)) }
+
+func (v *sourceRef) appendTo(buf []byte) []byte {
+	buf = append(buf, v.file...)
+	buf = append(buf, ':')
+	return strconv.AppendInt(buf, int64(v.line), 10)
+}
+
+// timeRef is a time with format stored inside logRecord.
+type timeRef struct {
+	t      time.Time
+	format string
+}
+
+func (v *timeRef) String() string { return v.t.Format(v.format) }
+
+func (v *timeRef) appendTo(buf []byte) []byte { return v.t.AppendFormat(buf, v.format) }
+
+// formatter formats logRecord using Logger's settings.
+type formatter struct {
+	format     Format
+	lay        *layout
+	timeFormat string
+	typedJSON  bool
+	color      bool
+	sanitize   bool
+	msgWidth   int
+}
+
+// formatter returns formatter for l's current settings.
+//
+// mergeParent must be called before formatter.
+func (l *Logger) formatter() formatter {
+	return formatter{
+		format:     *l.format,
+		lay:        l.layout,
+		timeFormat: *l.timeFormat,
+		typedJSON:  *l.typedJSON,
+		color:      l.useColor(),
+		sanitize:   *l.sanitize,
+		msgWidth:   *l.messageWidth,
+	}
+}
+
+// appendRecord appends r in f's format. Layout of r must be f.lay.
+func (f formatter) appendRecord(buf []byte, r *logRecord) []byte {
+	r.time.format = f.timeFormat
+	r.utc = timeRef{t: r.time.t.UTC(), format: f.timeFormat}
+	switch f.format {
+	case Text:
+		return r.appendText(buf, f)
+	case Logfmt:
+		return r.appendLogfmt(buf)
+	default:
+		return r.appendJSON(buf, f.typedJSON)
+	}
+}
+
+// appendText appends r in Text format, using f's color, sanitize and
+// msgWidth settings.
+func (r *logRecord) appendText(buf []byte, f formatter) []byte {
+	start, padFrom, padTo := len(buf), 0, -1
+	appendKeyVal := func(kf *keyFormat, key string, val any) {
+		if f.color || f.sanitize {
+			buf = r.appendStyledKeyVal(buf, kf, key, val, f.color, f.sanitize)
+		} else {
+			buf = kf.appendKeyVal(buf, key, val)
+		}
+		if f.msgWidth > 0 && key == KeyMessage {
+			padFrom = len(buf)
+			buf = appendPadding(buf, f.msgWidth-textWidth(buf[start:]))
+			padTo = len(buf)
+		}
+	}
+	for _, k := range r.lay.prefix {
+		if sv := r.surround[k.idx]; sv.ok {
+			appendKeyVal(k.format, k.key, sv.val)
+		}
+	}
+	for _, m := range r.middle {
+		appendKeyVal(r.lay.format(m.key), m.key, m.val)
+	}
+	for _, k := range r.lay.suffix {
+		if sv := r.surround[k.idx]; sv.ok {
+			appendKeyVal(k.format, k.key, sv.val)
+		}
+	}
+	if len(buf) == padTo { // Nothing after padding.
+		buf = buf[:padFrom]
+	}
+	return buf
+}
+
+// eachOrdered calls f for each key/value in order used by JSON and Logfmt
+// formats: same as Text plus KeyTime before and KeyLevel after
+// prefixKeys if they aren't in prefixKeys/suffixKeys. KeyTime (in UTC)
+// and KeyLevel are always reported using r's time and level (KeyTime
+// isn't reported if time is zero). Each key will be reported just once.
+func (r *logRecord) eachOrdered(f func(k string, v any)) {
+	_, timeIsSurround := r.lay.surround[KeyTime]
+	_, levelIsSurround := r.lay.surround[KeyLevel]
+	hasTime := !r.time.t.IsZero()
+	if !timeIsSurround && hasTime {
+		f(KeyTime, &r.utc)
+	}
+	afterPrefix := func() {
+		if !levelIsSurround {
+			f(KeyLevel, r.level)
+		}
+	}
+	r.each(func(k string, v any) {
+		switch {
+		case k == KeyTime && timeIsSurround:
+			if hasTime {
+				f(k, &r.utc)
+			}
+		case k == KeyLevel && levelIsSurround:
+			f(k, r.level)
+		case k != KeyTime && k != KeyLevel:
+			f(k, v)
+		}
+	}, afterPrefix, true)
+}
+
+// each calls f for each key/value in order used by Text format and calls
+// afterPrefix (if not nil) after prefixKeys. Each key will be reported
+// just once.
+//
+// If all is true then KeyTime and KeyLevel in prefixKeys/suffixKeys will
+// be reported even if they have no value.
+func (r *logRecord) each(f func(k string, v any), afterPrefix func(), all bool) {
+	if cap(r.emitted) < len(r.surround) {
+		r.emitted = make([]bool, len(r.surround))
+	} else {
+		r.emitted = r.emitted[:len(r.surround)]
+		clear(r.emitted)
+	}
+	eachSurround := func(keys []layoutKey) {
+		for _, k := range keys {
+			sv := r.surround[k.idx]
+			ok := sv.ok || all && (k.key == KeyTime || k.key == KeyLevel)
+			if ok && !r.emitted[k.idx] {
+				r.emitted[k.idx] = true
+				f(k.key, sv.val)
+			}
+		}
+	}
+	eachSurround(r.lay.prefix)
+	if afterPrefix != nil {
+		afterPrefix()
+	}
+	for _, m := range r.middle {
+		if idx, ok := r.lay.surround[m.key]; ok {
+			if r.emitted[idx] {
+				continue
+			}
+			r.emitted[idx] = true
+		}
+		f(m.key, m.val)
+	}
+	eachSurround(r.lay.suffix)
+}
+
+// appendJSON appends r in JSON format.
+//
+// If typed is false then all values are output as strings in the manner
+// of fmt.Sprint, otherwise see appendJSONTyped.
+func (r *logRecord) appendJSON(buf []byte, typed bool) []byte {
+	buf = append(buf, '{')
+	first := true
+	r.eachOrdered(func(k string, v any) {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = appendJSONString(buf, k)
+		buf = append(buf, ':')
+		if _, isStr := v.(string); typed && (isStr || k != KeyMessage) { // Avoid marshalling non-string in msg.
+			buf = r.appendJSONTyped(buf, v)
+		} else {
+			r.scratch = verbV.appendArg(r.scratch[:0], v)
+			buf = appendJSONString(buf, r.scratch)
+		}
+	})
+	return append(buf, '}')
+}
+
+// appendJSONTyped appends v as JSON value of v's own type if possible,
+// otherwise v in the manner of fmt.Sprint as JSON string.
+func (r *logRecord) appendJSONTyped(buf []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return appendJSONString(buf, v)
+	case *strRef, *sourceRef, *timeRef, Level:
+		r.scratch = verbV.appendArg(r.scratch[:0], v)
+		return appendJSONString(buf, r.scratch)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	}
+	return append(buf, marshalTyped(v)...)
+}
+
+// appendLogfmt appends r in Logfmt format.
+func (r *logRecord) appendLogfmt(buf []byte) []byte {
+	first := true
+	r.eachOrdered(func(k string, v any) {
+		if !first {
+			buf = append(buf, ' ')
+		}
+		first = false
+		buf = appendLogfmtKey(buf, k)
+		buf = append(buf, '=')
+		r.scratch = verbV.appendArg(r.scratch[:0], v)
+		buf = appendLogfmtValue(buf, r.scratch)
+	})
+	return buf
+}
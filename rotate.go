@@ -0,0 +1,378 @@
+package structlog
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotatedTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFile is an [io.Writer] (suitable for SetOutput) which appends
+// to a file and rotates it by size and/or time.
+//
+// Rotated files are renamed to name-TIME.ext (e.g. app-2006-01-02T15-04-05.000.log
+// for app.log), optionally compressed (name-TIME.ext.gz) and removed
+// when there are too many or too old rotated files.
+//
+// File will be opened on first Write.
+type RotatingFile struct {
+	mu       sync.Mutex
+	name     string
+	maxSize  int64
+	interval time.Duration
+	maxFiles int
+	maxAge   time.Duration
+	compress bool
+	fallback Printer
+	f        *os.File
+	size     int64
+	rotateAt time.Time
+	bg       sync.WaitGroup
+	bgMu     sync.Mutex // Serialize compression and removing of old files.
+	signals  chan os.Signal
+	done     chan struct{}
+}
+
+// NewRotatingFile returns RotatingFile which writes to file name.
+//
+// By default it won't rotate file (use SetMaxSize and/or SetInterval) and
+// won't remove rotated files (use SetMaxFiles and/or SetMaxAge).
+func NewRotatingFile(name string) *RotatingFile {
+	return &RotatingFile{
+		name:     name,
+		fallback: PrinterFunc(log.Print),
+	}
+}
+
+// SetMaxSize makes r rotate file before write which makes it larger than
+// size bytes (0 disables rotation by size).
+//
+// It returns r just for convenience.
+func (r *RotatingFile) SetMaxSize(size int64) *RotatingFile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxSize = size
+	return r
+}
+
+// SetInterval makes r rotate file on write after each interval (0
+// disables rotation by time). Rotation time is aligned to interval
+// (since zero time, in UTC), e.g. with 24h interval file will be rotated
+// after midnight UTC.
+//
+// It returns r just for convenience.
+func (r *RotatingFile) SetInterval(interval time.Duration) *RotatingFile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interval = interval
+	if r.f != nil {
+		r.rotateAt = r.nextRotateAt(time.Now())
+	}
+	return r
+}
+
+// SetMaxFiles makes r keep at most n rotated files (0 means no limit).
+//
+// It returns r just for convenience.
+func (r *RotatingFile) SetMaxFiles(n int) *RotatingFile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxFiles = n
+	return r
+}
+
+// SetMaxAge makes r remove rotated files older than age (0 means no
+// limit).
+//
+// It returns r just for convenience.
+func (r *RotatingFile) SetMaxAge(age time.Duration) *RotatingFile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxAge = age
+	return r
+}
+
+// SetCompress makes r compress rotated files using gzip in background.
+//
+// It returns r just for convenience.
+func (r *RotatingFile) SetCompress(enable bool) *RotatingFile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.compress = enable
+	return r
+}
+
+// SetFallback changes Printer used to output errors happens in
+// background while compressing or removing rotated files (default value
+// is PrinterFunc(log.Print)).
+//
+// It returns r just for convenience.
+func (r *RotatingFile) SetFallback(fallback Printer) *RotatingFile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = fallback
+	return r
+}
+
+// ReopenOnSIGHUP makes r call Reopen on SIGHUP, for compatibility with
+// external tools like logrotate. It'll stop on Close. It does nothing on
+// systems without SIGHUP (non-Unix).
+//
+// It returns r just for convenience.
+func (r *RotatingFile) ReopenOnSIGHUP() *RotatingFile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.signals != nil {
+		return r
+	}
+	r.signals = make(chan os.Signal, 1)
+	r.done = make(chan struct{})
+	notifySIGHUP(r.signals)
+	go func(signals <-chan os.Signal, done <-chan struct{}) {
+		for {
+			select {
+			case <-signals:
+				if err := r.Reopen(); err != nil {
+					r.printErr(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}(r.signals, r.done)
+	return r
+}
+
+// Write implements [io.Writer]. It rotates file before write if needed.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.needRotate(len(p)) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates file now.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	return r.rotate()
+}
+
+// Reopen closes file. It will be reopened on next Write. Use it after
+// file was renamed or removed by external tool.
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeFile()
+}
+
+// Close closes file and waits until background compression and removing
+// of rotated files will be finished. File will be reopened on next Write.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	if r.signals != nil {
+		signal.Stop(r.signals)
+		close(r.done)
+		r.signals, r.done = nil, nil
+	}
+	err := r.closeFile()
+	r.mu.Unlock()
+	r.bg.Wait()
+	return err
+}
+
+// open must be called with r.mu locked.
+func (r *RotatingFile) open() error {
+	const perm = 0o644
+	f, err := os.OpenFile(r.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.f = f
+	r.size = fi.Size()
+	r.rotateAt = r.nextRotateAt(time.Now())
+	return nil
+}
+
+// closeFile must be called with r.mu locked.
+func (r *RotatingFile) closeFile() error {
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// nextRotateAt must be called with r.mu locked.
+func (r *RotatingFile) nextRotateAt(t time.Time) time.Time {
+	if r.interval <= 0 {
+		return time.Time{}
+	}
+	return t.Truncate(r.interval).Add(r.interval)
+}
+
+// needRotate must be called with r.mu locked.
+func (r *RotatingFile) needRotate(size int) bool {
+	if now := time.Now(); !r.rotateAt.IsZero() && !now.Before(r.rotateAt) {
+		if r.size > 0 {
+			return true
+		}
+		r.rotateAt = r.nextRotateAt(now) // Do not rotate empty file.
+	}
+	return r.size > 0 && r.maxSize > 0 && r.size+int64(size) > r.maxSize
+}
+
+// rotate must be called with r.mu locked and opened file.
+func (r *RotatingFile) rotate() error {
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+	rotated, err := r.rotatedName(time.Now())
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(r.name, rotated); err != nil {
+		return err
+	}
+	if err = r.open(); err != nil {
+		return err
+	}
+	if r.compress || r.maxFiles > 0 || r.maxAge > 0 {
+		compress, maxFiles, maxAge := r.compress, r.maxFiles, r.maxAge
+		r.bg.Go(func() {
+			r.bgMu.Lock()
+			defer r.bgMu.Unlock()
+			if compress {
+				r.printErr(compressFile(rotated))
+			}
+			r.printErr(r.removeOld(maxFiles, maxAge))
+		})
+	}
+	return nil
+}
+
+// rotatedName returns unused name for file rotated at t.
+func (r *RotatingFile) rotatedName(t time.Time) (string, error) {
+	prefix, ext := r.rotatedPrefixExt()
+	for {
+		name := prefix + t.Format(rotatedTimeFormat) + ext
+		_, err := os.Lstat(name)
+		_, errGz := os.Lstat(name + ".gz")
+		switch {
+		case err == nil || errGz == nil:
+			t = t.Add(time.Millisecond)
+		case errors.Is(err, os.ErrNotExist):
+			return name, nil
+		default:
+			return "", err
+		}
+	}
+}
+
+func (r *RotatingFile) rotatedPrefixExt() (prefix, ext string) {
+	ext = filepath.Ext(r.name)
+	return strings.TrimSuffix(r.name, ext) + "-", ext
+}
+
+// removeOld removes rotated files if there are more than maxFiles or
+// they are older than maxAge.
+func (r *RotatingFile) removeOld(maxFiles int, maxAge time.Duration) error {
+	if maxFiles <= 0 && maxAge <= 0 {
+		return nil
+	}
+	type rotatedFile struct {
+		name string
+		t    time.Time
+	}
+	prefix, ext := r.rotatedPrefixExt()
+	dir, prefix := filepath.Split(prefix)
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return err
+	}
+	var files []rotatedFile
+	for _, entry := range entries {
+		name := entry.Name()
+		ts, ok := strings.CutPrefix(name, prefix)
+		ts = strings.TrimSuffix(strings.TrimSuffix(ts, ".gz"), ext)
+		if t, err := time.ParseInLocation(rotatedTimeFormat, ts, time.Local); ok && err == nil {
+			files = append(files, rotatedFile{name: filepath.Join(dir, name), t: t})
+		}
+	}
+	slices.SortFunc(files, func(a, b rotatedFile) int { return b.t.Compare(a.t) })
+	var errs []error
+	for i, file := range files {
+		if maxFiles > 0 && i >= maxFiles || maxAge > 0 && time.Since(file.t) > maxAge {
+			errs = append(errs, os.Remove(file.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *RotatingFile) printErr(err error) {
+	if err == nil {
+		return
+	}
+	r.mu.Lock()
+	fallback := r.fallback
+	r.mu.Unlock()
+	fallback.Print(fmt.Sprintf("rotate %s: %v", r.name, err))
+}
+
+// compressFile replaces file name with name.gz.
+func compressFile(name string) (err error) {
+	src, err := os.Open(name) //nolint:gosec // By design.
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	const perm = 0o644
+	dst, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm) //nolint:gosec // By design.
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(dst.Name())
+		}
+	}()
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	err = errors.Join(err, zw.Close(), dst.Close())
+	if err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
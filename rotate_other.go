@@ -0,0 +1,8 @@
+//go:build !unix
+
+package structlog
+
+import "os"
+
+// notifySIGHUP does nothing because SIGHUP is not supported on this OS.
+func notifySIGHUP(chan<- os.Signal) {}
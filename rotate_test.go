@@ -0,0 +1,134 @@
+package structlog_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+// rotated returns names of rotated files for dir/app.log.
+func rotated(t *check.C, dir string) []string {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, "app-*.log*"))
+	t.Nil(err)
+	slices.Sort(names)
+	return names
+}
+
+func readFile(t *check.C, name string) string {
+	t.Helper()
+	buf, err := os.ReadFile(name) //nolint:gosec // False positive.
+	t.Nil(err)
+	return string(buf)
+}
+
+func TestRotatingFileSize(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	w := structlog.NewRotatingFile(name).SetMaxSize(8)
+	log := structlog.NewZeroLogger().SetOutput(w).SetKeyValFormat("%[2]v")
+	log.Info("first")
+	log.Info("second")
+	log.Info("3")
+	log.Info("4")
+	t.Nil(w.Close())
+
+	t.Equal(readFile(t, name), "3\n4\n")
+	names := rotated(t, dir)
+	t.Must(t.Len(names, 2))
+	t.Equal(readFile(t, names[0]), "first\n")
+	t.Equal(readFile(t, names[1]), "second\n")
+	t.Match(filepath.Base(names[0]), `^app-\d{4}-\d\d-\d\dT\d\d-\d\d-\d\d\.\d{3}\.log$`)
+
+	w.SetMaxFiles(1).SetCompress(true)
+	t.Nil(w.Rotate())
+	t.Nil(w.Close())
+	names = rotated(t, dir)
+	t.Must(t.Len(names, 1))
+	t.Match(names[0], `\.log\.gz$`)
+	f, err := os.Open(names[0])
+	t.Nil(err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	t.Nil(err)
+	buf, err := io.ReadAll(zr)
+	t.Nil(err)
+	t.Equal(string(buf), "3\n4\n")
+	t.Equal(readFile(t, name), "")
+}
+
+func TestRotatingFileInterval(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	const interval = 50 * time.Millisecond
+	w := structlog.NewRotatingFile(name).SetInterval(interval)
+	defer w.Close()
+
+	_, err := w.Write([]byte("1\n"))
+	t.Nil(err)
+	time.Sleep(time.Until(time.Now().Truncate(interval).Add(interval)))
+	_, err = w.Write([]byte("2\n"))
+	t.Nil(err)
+	_, err = w.Write([]byte("3\n"))
+	t.Nil(err)
+
+	t.Equal(readFile(t, name), "2\n3\n")
+	names := rotated(t, dir)
+	t.Must(t.Len(names, 1))
+	t.Equal(readFile(t, names[0]), "1\n")
+}
+
+func TestRotatingFileMaxAge(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	for _, file := range []string{"app-2001-01-01T00-00-00.000.log.gz", "app-bad.log", "other.log"} {
+		t.Nil(os.WriteFile(filepath.Join(dir, file), nil, 0o600))
+	}
+	w := structlog.NewRotatingFile(name).SetMaxAge(24 * time.Hour)
+	_, err := w.Write([]byte("1\n"))
+	t.Nil(err)
+	t.Nil(w.Rotate())
+	t.Nil(w.Close())
+
+	names := rotated(t, dir)
+	t.Must(t.Len(names, 2))
+	t.Equal(readFile(t, names[0]), "1\n")
+	t.Equal(filepath.Base(names[1]), "app-bad.log")
+	_, err = os.Stat(filepath.Join(dir, "other.log"))
+	t.Nil(err)
+}
+
+func TestRotatingFileReopen(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	w := structlog.NewRotatingFile(name)
+	defer w.Close()
+
+	_, err := w.Write([]byte("1\n"))
+	t.Nil(err)
+	t.Nil(os.Rename(name, name+".1"))
+	_, err = w.Write([]byte("2\n"))
+	t.Nil(err)
+	t.Nil(w.Reopen())
+	_, err = w.Write([]byte("3\n"))
+	t.Nil(err)
+
+	t.Equal(readFile(t, name+".1"), "1\n2\n")
+	t.Equal(readFile(t, name), "3\n")
+}
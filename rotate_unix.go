@@ -0,0 +1,14 @@
+//go:build unix
+
+package structlog
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifySIGHUP makes package signal relay SIGHUP to c.
+func notifySIGHUP(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGHUP)
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package structlog_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestRotatingFileSIGHUP(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	w := structlog.NewRotatingFile(name).ReopenOnSIGHUP()
+	defer w.Close()
+
+	_, err := w.Write([]byte("old\n"))
+	t.Nil(err)
+	t.Nil(os.Rename(name, name+".1"))
+	t.Nil(syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		_, err = w.Write([]byte("line\n"))
+		t.Nil(err)
+		if _, err = os.Stat(name); err == nil {
+			break
+		}
+	}
+	t.Equal(readFile(t, name), "line\n")
+	t.HasPrefix(readFile(t, name+".1"), "old\n")
+}
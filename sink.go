@@ -0,0 +1,168 @@
+package structlog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Record is a log record passed to Sink.
+type Record struct {
+	Time    time.Time // Time of log record (not in UTC).
+	Level   Level
+	Message any    // Value of KeyMessage, usually a string or an error.
+	Unit    string // Value of KeyUnit, empty if it won't be output.
+	Func    string // Value of KeyFunc, empty if it won't be output.
+	Source  string // Value of KeySource (file:line), empty if it won't be output.
+	Stack   string // Value of KeyStack, empty if it won't be output.
+	// Keyvals contains all keys and values which would be output in Text
+	// format (including service keys like KeyMessage, KeyUnit, etc.)
+	// in same order as Text format, each key just once.
+	//
+	// KeyTime value is a time.Time (if KeyTime would be output).
+	Keyvals []any
+}
+
+// Get returns value for key k from r.Keyvals.
+func (r *Record) Get(k string) (any, bool) {
+	for i := 0; i+1 < len(r.Keyvals); i += 2 {
+		if r.Keyvals[i] == k {
+			return r.Keyvals[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// Sink is used by Logger to output log records without formatting them.
+// Use SetSink or SetPrinter (with Printer which also implements Sink) to
+// make Logger use a Sink.
+type Sink interface {
+	// Handle outputs r. It must not keep r (including r.Keyvals) after
+	// return.
+	Handle(r *Record)
+}
+
+// SinkFunc is an adapter to allow the use of an ordinary function as a
+// Sink.
+type SinkFunc func(r *Record)
+
+// Handle implements Sink.
+func (f SinkFunc) Handle(r *Record) { f(r) }
+
+// Formatter formats Record as a log line (without trailing \n).
+type Formatter interface {
+	AppendRecord(buf []byte, r *Record) []byte
+}
+
+// Formatter returns Formatter which formats records using l's current
+// format settings (SetLogFormat, SetKeysFormat, SetPrefixKeys,
+// SetTimeFormat, etc.), in the same way as l outputs log records.
+//
+// Formatter uses Record's Time, Level and Keyvals.
+func (l *Logger) Formatter() Formatter {
+	l.enabled(DBG) // Call mergeParent.
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return recordFormatter{f: l.formatter()}
+}
+
+type recordFormatter struct{ f formatter }
+
+// AppendRecord implements Formatter.
+func (rf recordFormatter) AppendRecord(buf []byte, rec *Record) []byte {
+	r := getRecord(rf.f.lay)
+	defer putRecord(r)
+	r.time.t = rec.Time
+	r.level = rec.Level
+	r.update(KeyLevel, rec.Level)
+	for i := 0; i+1 < len(rec.Keyvals); i += 2 {
+		k, ok := rec.Keyvals[i].(string)
+		if !ok {
+			k = fmt.Sprint(rec.Keyvals[i])
+		}
+		if t, ok := rec.Keyvals[i+1].(time.Time); ok && k == KeyTime {
+			r.time.t = t
+			r.set(k, &r.time)
+		} else {
+			r.set(k, rec.Keyvals[i+1])
+		}
+	}
+	return rf.f.appendRecord(buf, r)
+}
+
+// PrinterSink returns Sink which formats records using f and outputs
+// them using p.
+func PrinterSink(p Printer, f Formatter) Sink {
+	return &printerSink{p: p, f: f}
+}
+
+type printerSink struct {
+	p Printer
+	f Formatter
+}
+
+// Handle implements Sink.
+func (s *printerSink) Handle(r *Record) {
+	printLine(s.p, r.Level, s.f.AppendRecord(nil, r))
+}
+
+// SetSink changes log output destination to s. Sink will be called with
+// unformatted log records, so l's format settings won't be used.
+//
+// It doesn't creates a new logger, it returns l just for convenience.
+func (l *Logger) SetSink(s Sink) *Logger {
+	return l.SetPrinter(sinkPrinter{s})
+}
+
+// sinkPrinter is a Printer used by SetSink.
+type sinkPrinter struct{ Sink }
+
+// Print outputs v formatted in the manner of [fmt.Print] as a message
+// with INF level.
+func (p sinkPrinter) Print(v ...any) { p.Handle(printRecord(v...)) }
+
+// printRecord returns Record for Printer.Print arguments.
+func printRecord(v ...any) *Record {
+	msg := strings.TrimSuffix(fmt.Sprint(v...), "\n")
+	return &Record{
+		Time:    now(),
+		Level:   INF,
+		Message: msg,
+		Keyvals: []any{KeyMessage, msg},
+	}
+}
+
+// export returns r as Record. Result is valid until putRecord(r).
+func (r *logRecord) export() *Record {
+	rec := &r.rec
+	if len(rec.Keyvals) > 0 { // Already exported.
+		return rec
+	}
+	rec.Time = r.time.t
+	rec.Level = r.level
+	r.each(func(k string, v any) {
+		switch v := v.(type) {
+		case *strRef:
+			rec.Keyvals = append(rec.Keyvals, k, v.s)
+		case *sourceRef:
+			rec.Keyvals = append(rec.Keyvals, k, v.String())
+		case *timeRef:
+			rec.Keyvals = append(rec.Keyvals, k, v.t)
+		default:
+			rec.Keyvals = append(rec.Keyvals, k, v)
+		}
+		switch k {
+		case KeyMessage:
+			rec.Message = v
+		case KeyUnit:
+			rec.Unit = fmt.Sprint(rec.Keyvals[len(rec.Keyvals)-1])
+		case KeyFunc:
+			rec.Func = fmt.Sprint(rec.Keyvals[len(rec.Keyvals)-1])
+		case KeySource:
+			rec.Source = fmt.Sprint(rec.Keyvals[len(rec.Keyvals)-1])
+		case KeyStack:
+			rec.Stack = fmt.Sprint(v)
+		}
+	}, nil, false)
+	return rec
+}
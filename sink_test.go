@@ -0,0 +1,100 @@
+package structlog_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestSink(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var got []structlog.Record
+	log := structlog.New(structlog.KeyTime, structlog.Auto).SetSink(structlog.SinkFunc(func(r *structlog.Record) {
+		rec := *r
+		rec.Keyvals = slices.Clone(r.Keyvals)
+		got = append(got, rec)
+	}))
+	log.Warn("hello", "k", 1, structlog.KeyStack, "stack")
+	log.New(structlog.KeyUnit, nil, structlog.KeyFunc, nil, structlog.KeySource, nil).Print("plain")
+
+	t.Must(t.Len(got, 2))
+	ts := got[0].Time
+	t.Equal(ts.Format(time.RFC3339Nano), "2020-01-02T03:04:05.123456789+01:00")
+	t.DeepEqual(got[0], structlog.Record{
+		Time:    ts,
+		Level:   structlog.WRN,
+		Message: "hello",
+		Unit:    unit,
+		Func:    "structlog_test.TestSink",
+		Source:  "sink_test.go:22",
+		Stack:   "stack",
+		Keyvals: []any{
+			structlog.KeyTime, ts,
+			structlog.KeyApp, "structlog.test",
+			structlog.KeyPID, got[0].Keyvals[5],
+			structlog.KeyLevel, structlog.WRN,
+			structlog.KeyUnit, unit,
+			structlog.KeyMessage, "hello",
+			"k", 1,
+			structlog.KeyFunc, "structlog_test.TestSink",
+			structlog.KeySource, "sink_test.go:22",
+			structlog.KeyStack, "stack",
+		},
+	})
+	t.Equal(string(structlog.New().Formatter().AppendRecord(nil, &got[0])),
+		"Jan  2 03:04:05.123456 structlog.test["+pid+"] WRN "+unit+": `hello` k=1 \t@ structlog_test.TestSink(sink_test.go:22)\nstack")
+	t.Equal(got[1].Level, structlog.INF)
+	t.Equal(got[1].Message, "plain")
+	t.Zero(got[1].Unit)
+	v, ok := got[1].Get(structlog.KeyMessage)
+	t.True(ok)
+	t.Equal(v, "plain")
+	_, ok = got[1].Get(structlog.KeyUnit)
+	t.False(ok)
+}
+
+func TestFormatter(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	rec := &structlog.Record{
+		Time:  time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("X", 3600)),
+		Level: structlog.ERR,
+		Keyvals: []any{
+			structlog.KeyTime, time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("X", 3600)),
+			structlog.KeyUnit, "db",
+			structlog.KeyMessage, "failed",
+			"n", 42,
+			structlog.KeySource, "db.go:12",
+		},
+	}
+	tests := []struct {
+		log  *structlog.Logger
+		want string
+	}{
+		{
+			structlog.New(),
+			"Jan  2 03:04:05.000000  ERR db: `failed` n=42(db.go:12)\n",
+		},
+		{
+			structlog.New().SetLogFormat(structlog.JSON).SetTypedJSON(true),
+			`{"_t":"Jan  2 02:04:05.000000","_l":"ERR","_u":"db","_m":"failed","n":42,"_s":"db.go:12"}` + "\n",
+		},
+		{
+			structlog.NewZeroLogger().SetLogFormat(structlog.Logfmt).SetTimeFormat(time.Kitchen),
+			`_t=2:04AM _l=ERR _u=db _m=failed n=42 _s=db.go:12` + "\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run("", func(tt *testing.T) {
+			t := check.T(tt)
+			var buf bufPrinter
+			structlog.PrinterSink(&buf, tc.log.Formatter()).Handle(rec)
+			t.Equal(buf.String(), tc.want)
+		})
+	}
+}
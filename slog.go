@@ -0,0 +1,179 @@
+package structlog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+)
+
+// SlogHandler implements [slog.Handler] which outputs records using
+// Logger, so records logged using [log/slog] will have same format as
+// records logged using Logger itself.
+//
+// Levels are mapped to Level with same value if it's predefined or
+// registered by RegisterLevel, otherwise to nearest lower one (TRC for
+// levels below TRC). E.g. [slog.LevelInfo]+2 is mapped to INF.
+//
+// Attrs are output as keyvals in call order, groups are flattened using
+// "group.key" key names.
+//
+// Use NewSlogLogger to get [slog.Logger] using this handler.
+type SlogHandler struct {
+	log     *Logger
+	keyvals []any
+	group   string
+}
+
+// NewSlogLogger returns a new [slog.Logger] which will output records
+// using l.
+func NewSlogLogger(l *Logger) *slog.Logger {
+	return slog.New(NewSlogHandler(l))
+}
+
+// NewSlogHandler returns a new handler which will output records using l.
+func NewSlogHandler(l *Logger) *SlogHandler {
+	if l == nil {
+		panic("NewSlogHandler called with nil *Logger")
+	}
+	return &SlogHandler{log: l}
+}
+
+// Enabled implements [slog.Handler].
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.log.enabled(levelFromSlog(level))
+}
+
+// Handle implements [slog.Handler].
+//
+// Caller's details (KeyUnit, KeyFunc and KeySource) are calculated using
+// r.PC. If r.Time is zero then KeyTime won't be output.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error { //nolint:gocritic // Interface requires passing by value.
+	const pairSize = 2
+	keyvals := make([]any, len(h.keyvals), len(h.keyvals)+r.NumAttrs()*pairSize)
+	copy(keyvals, h.keyvals)
+	r.Attrs(func(a slog.Attr) bool {
+		keyvals = appendAttr(keyvals, h.group, a)
+		return true
+	})
+	h.log.output(levelFromSlog(r.Level), &r.Time, r.PC, r.Message, keyvals...)
+	return nil
+}
+
+// WithAttrs implements [slog.Handler].
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	keyvals := append([]any(nil), h.keyvals...)
+	for _, a := range attrs {
+		keyvals = appendAttr(keyvals, h.group, a)
+	}
+	return &SlogHandler{log: h.log, keyvals: keyvals, group: h.group}
+}
+
+// WithGroup implements [slog.Handler].
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{log: h.log, keyvals: h.keyvals, group: h.group + name + "."}
+}
+
+func levelFromSlog(level slog.Level) Level {
+	l := Level(max(min(level, math.MaxInt8), math.MinInt8))
+	for ; l > TRC; l-- {
+		if l.String() != unknown {
+			return l
+		}
+	}
+	return TRC
+}
+
+// appendAttr appends a to keyvals as key/value pair(s) with key prefixed
+// by group. It resolves a's value and flattens groups, empty attrs and
+// empty groups are ignored.
+func appendAttr(keyvals []any, group string, a slog.Attr) []any {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return keyvals
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(keyvals, group+a.Key, a.Value.Any())
+	}
+	if a.Key != "" {
+		group += a.Key + "."
+	}
+	for _, ga := range a.Value.Group() {
+		keyvals = appendAttr(keyvals, group, ga)
+	}
+	return keyvals
+}
+
+// expandAttrs returns keyvals with each [slog.Attr] used in place of key
+// replaced by key/value pair. Values are not resolved.
+func expandAttrs(keyvals []any) []any {
+	i := 0
+	for ; i < len(keyvals); i += 2 {
+		if _, ok := keyvals[i].(slog.Attr); ok {
+			break
+		}
+	}
+	if i >= len(keyvals) {
+		return keyvals
+	}
+	res := append(make([]any, 0, len(keyvals)+1), keyvals[:i]...)
+	for ; i < len(keyvals); i++ {
+		if a, ok := keyvals[i].(slog.Attr); ok {
+			res = append(res, a.Key, a.Value)
+			continue
+		}
+		res = append(res, keyvals[i])
+		if i+1 < len(keyvals) {
+			i++
+			res = append(res, keyvals[i])
+		}
+	}
+	return res
+}
+
+// resolveKeyvals returns keyvals with [slog.Value] and [slog.LogValuer]
+// values resolved and groups flattened using "key.subkey" key names.
+func resolveKeyvals(keyvals []any) []any {
+	i := 1
+	for ; i < len(keyvals); i += 2 {
+		if isSlogValue(keyvals[i]) {
+			break
+		}
+	}
+	if i >= len(keyvals) {
+		return keyvals
+	}
+	res := append(make([]any, 0, len(keyvals)), keyvals[:i-1]...)
+	for i--; i < len(keyvals); i += 2 {
+		k, ok := keyvals[i].(string)
+		if ok && isSlogValue(keyvals[i+1]) {
+			res = appendAttr(res, "", slog.Any(k, keyvals[i+1]))
+		} else {
+			res = append(res, keyvals[i], keyvals[i+1])
+		}
+	}
+	return res
+}
+
+// resolveValue returns resolved v if it's [slog.Value] or
+// [slog.LogValuer], otherwise v.
+func resolveValue(v any) any {
+	if !isSlogValue(v) {
+		return v
+	}
+	return slog.AnyValue(v).Resolve().Any()
+}
+
+func isSlogValue(v any) bool {
+	switch v.(type) {
+	case slog.Value, slog.LogValuer:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,112 @@
+package structlog_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func TestSlogHandler(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := slog.New(structlog.NewSlogHandler(structlog.New().SetOutput(&buf)))
+	log.Info("something happens", "k1", "v1", slog.Int("k2", 2))
+	log.Debug("details", slog.Group("req", "id", 42, slog.Group("", "n", 1)), slog.Group("empty"))
+	log.Log(context.Background(), slog.LevelWarn+1, "oops", slog.Attr{})
+	log.Log(context.Background(), slog.LevelError+4, "fatal")
+	t.Equal(buf.String(), ""+
+		"structlog.test["+pid+"] inf "+unit+": `something happens` k1=v1 k2=2 \t@ structlog_test.TestSlogHandler(slog_test.go:21)\n"+
+		"structlog.test["+pid+"] dbg "+unit+": `details` req.id=42 req.n=1 \t@ structlog_test.TestSlogHandler(slog_test.go:22)\n"+
+		"structlog.test["+pid+"] WRN "+unit+": `oops` \t@ structlog_test.TestSlogHandler(slog_test.go:23)\n"+
+		"structlog.test["+pid+"] CRT "+unit+": `fatal` \t@ structlog_test.TestSlogHandler(slog_test.go:24)\n")
+}
+
+func TestSlogHandlerWith(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := slog.New(structlog.NewSlogHandler(structlog.New().SetOutput(&buf)))
+	log1 := log.With("a", 1).WithGroup("g").With("b", 2)
+	log2 := log1.WithGroup("h")
+	log1.Warn("one", "c", 3)
+	log2.Warn("two", "c", 3)
+	log.Warn("zero")
+	t.Equal(buf.String(), ""+
+		"structlog.test["+pid+"] WRN "+unit+": `one` a=1 g.b=2 g.c=3 \t@ structlog_test.TestSlogHandlerWith(slog_test.go:39)\n"+
+		"structlog.test["+pid+"] WRN "+unit+": `two` a=1 g.b=2 g.h.c=3 \t@ structlog_test.TestSlogHandlerWith(slog_test.go:40)\n"+
+		"structlog.test["+pid+"] WRN "+unit+": `zero` \t@ structlog_test.TestSlogHandlerWith(slog_test.go:41)\n")
+}
+
+func TestSlogHandlerEnabled(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	h := structlog.NewSlogHandler(structlog.New().SetOutput(&buf).SetLogLevel(structlog.WRN))
+	t.False(h.Enabled(context.Background(), slog.LevelDebug))
+	t.False(h.Enabled(context.Background(), slog.LevelInfo))
+	t.True(h.Enabled(context.Background(), slog.LevelWarn))
+	t.True(h.Enabled(context.Background(), slog.LevelError))
+	slog.New(h).Info("skip")
+	t.Equal(buf.String(), "")
+	t.Panic(func() { structlog.NewSlogHandler(nil) })
+}
+
+type lazyValue struct{ calls *int }
+
+func (v lazyValue) LogValue() slog.Value {
+	*v.calls++
+	return slog.GroupValue(slog.Int("calls", *v.calls))
+}
+
+func TestSlogKeyvals(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	calls := 0
+	log := structlog.New(slog.String("x", "default")).SetOutput(&buf).SetLogLevel(structlog.INF).
+		PrependSuffixKeys("x")
+	log.Debug("skip", "lazy", lazyValue{&calls})
+	t.Zero(calls)
+	log.Info("attrs", slog.Int("a", 1), "b", slog.StringValue("two"), slog.Group("g", "c", 3), "lazy", lazyValue{&calls})
+	t.Equal(calls, 1)
+	err := log.WrapErr(io.EOF, slog.Bool("d", true))
+	log.Warn(err, "odd")
+	t.Equal(buf.String(), ""+
+		"structlog.test["+pid+"] inf "+unit+": `attrs` a=1 b=two g.c=3 lazy.calls=1 x=default \t@ structlog_test.TestSlogKeyvals(slog_test.go:78)\n"+
+		"structlog.test["+pid+"] ERR "+unit+": `odd keyvals` x=default \t@ structlog_test.TestSlogKeyvals(slog_test.go:81)\n"+
+		"structlog.test["+pid+"] WRN "+unit+": `EOF` d=true odd=(MISSING) x=default \t@ structlog_test.TestSlogKeyvals(slog_test.go:81)\n")
+}
+
+func TestNewSlogLogger(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := structlog.NewSlogLogger(structlog.New().SetOutput(&buf))
+	log.Info("msg", "k", "v")
+	t.Equal(buf.String(), "structlog.test["+pid+"] inf "+unit+": `msg` k=v \t@ structlog_test.TestNewSlogLogger(slog_test.go:93)\n")
+}
+
+func TestSlogHandlerZeroTime(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	var buf bytes.Buffer
+	log := structlog.New(structlog.KeyTime, structlog.Auto,
+		structlog.KeyUnit, nil, structlog.KeyFunc, nil, structlog.KeySource, nil).SetOutput(&buf)
+	h := structlog.NewSlogHandler(log)
+	t.Nil(h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "no time", 0)))
+	t.Nil(h.Handle(context.Background(), slog.NewRecord(time.Date(2020, time.January, 2, 3, 4, 5, 123456789, time.UTC), slog.LevelInfo, "time", 0)))
+	log.SetLogFormat(structlog.JSON)
+	t.Nil(h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "no time", 0)))
+	t.Equal(buf.String(), ""+
+		"structlog.test["+pid+"] inf `no time`\n"+
+		"Jan  2 03:04:05.123456 structlog.test["+pid+"] inf `time`\n"+
+		`{"_a":"structlog.test","_p":"`+pid+`","_l":"inf","_m":"no time"}`+"\n")
+}
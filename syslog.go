@@ -0,0 +1,436 @@
+package structlog
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogFormat is a syslog message format.
+type SyslogFormat byte
+
+// Syslog message formats.
+const (
+	RFC5424 SyslogFormat = iota
+	RFC3164
+)
+
+// SyslogFacility is a syslog facility code.
+type SyslogFacility byte
+
+// Syslog facilities.
+const (
+	SyslogKern SyslogFacility = iota
+	SyslogUser
+	SyslogMail
+	SyslogDaemon
+	SyslogAuth
+	SyslogSyslog
+	SyslogLPR
+	SyslogNews
+	SyslogUUCP
+	SyslogCron
+	SyslogAuthPriv
+	SyslogFTP
+	_ // NTP subsystem.
+	_ // Log audit.
+	_ // Log alert.
+	_ // Clock daemon.
+	SyslogLocal0
+	SyslogLocal1
+	SyslogLocal2
+	SyslogLocal3
+	SyslogLocal4
+	SyslogLocal5
+	SyslogLocal6
+	SyslogLocal7
+)
+
+// Defaults.
+const (
+	DefaultSyslogFormat   = RFC5424
+	DefaultSyslogFacility = SyslogUser
+	DefaultSyslogSDID     = "structlog@32473" // 32473 is an example enterprise number.
+)
+
+const syslogTimeout = 5 * time.Second
+
+var syslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"} //nolint:gochecknoglobals // Const.
+
+// SyslogPrinter is a Printer which sends log records to syslog server.
+//
+// When used by Logger it sends each log record this way:
+//
+//   - PRI: facility and severity calculated from log level (levels
+//     above CRT are sent as alert, CRT+8 and above as emergency)
+//   - TIMESTAMP: time of log record
+//   - APP-NAME: value of KeyApp (or current app name)
+//   - PROCID: value of KeyPID (or current PID)
+//   - STRUCTURED-DATA (RFC5424) or end of MSG (RFC3164): other keys
+//     which would be output by Logger, except KeyTime and KeyLevel
+//   - MSG: value of KeyMessage
+//
+// Logger's format settings (SetLogFormat, SetKeysFormat, etc.) are not
+// used by SyslogPrinter.
+//
+// Records are sent over stream connections (tcp and unix) using
+// octet-counting framing (RFC 6587) by default.
+type SyslogPrinter struct {
+	mu            sync.Mutex
+	network       string
+	addr          string
+	format        SyslogFormat
+	facility      SyslogFacility
+	hostname      string
+	sdID          string
+	octetCounting bool
+	fallback      Printer
+	conn          net.Conn
+	stream        bool
+}
+
+// NewSyslogPrinter returns a Printer which sends log records to syslog
+// server at addr using network ("udp", "tcp", "unix", "unixgram", etc.).
+// If network and addr are empty then local syslog server will be used
+// (/dev/log, /var/run/syslog or /var/run/log).
+//
+// Connection will be established on first use and re-established after
+// errors.
+func NewSyslogPrinter(network, addr string) *SyslogPrinter {
+	hostname, _ := os.Hostname()
+	return &SyslogPrinter{
+		network:       network,
+		addr:          addr,
+		format:        DefaultSyslogFormat,
+		facility:      DefaultSyslogFacility,
+		hostname:      hostname,
+		sdID:          DefaultSyslogSDID,
+		octetCounting: true,
+		fallback:      PrinterFunc(log.Print),
+	}
+}
+
+// SetFormat changes syslog message format (default value is
+// DefaultSyslogFormat).
+//
+// It returns p just for convenience.
+func (p *SyslogPrinter) SetFormat(format SyslogFormat) *SyslogPrinter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.format = format
+	return p
+}
+
+// SetFacility changes syslog facility (default value is
+// DefaultSyslogFacility).
+//
+// It returns p just for convenience.
+func (p *SyslogPrinter) SetFacility(facility SyslogFacility) *SyslogPrinter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.facility = facility
+	return p
+}
+
+// SetHostname changes HOSTNAME (default value is [os.Hostname]).
+//
+// It returns p just for convenience.
+func (p *SyslogPrinter) SetHostname(hostname string) *SyslogPrinter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.hostname = hostname
+	return p
+}
+
+// SetStructuredDataID changes SD-ID used to send keyvals in RFC5424
+// format (default value is DefaultSyslogSDID).
+//
+// It returns p just for convenience.
+func (p *SyslogPrinter) SetStructuredDataID(sdID string) *SyslogPrinter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sdID = sdID
+	return p
+}
+
+// SetOctetCounting changes framing used for stream connections: either
+// octet-counting (default) or non-transparent framing (each message ends
+// with LF, so LF inside message is escaped as "\n").
+//
+// It returns p just for convenience.
+func (p *SyslogPrinter) SetOctetCounting(enable bool) *SyslogPrinter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.octetCounting = enable
+	return p
+}
+
+// SetFallback changes Printer used to output messages which failed to be
+// sent to syslog server together with error (default value is
+// PrinterFunc(log.Print)).
+//
+// It returns p just for convenience.
+func (p *SyslogPrinter) SetFallback(fallback Printer) *SyslogPrinter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fallback = fallback
+	return p
+}
+
+// Print implements Printer. It sends v formatted in the manner of
+// [fmt.Print] as a message with INF level.
+func (p *SyslogPrinter) Print(v ...any) { p.Handle(printRecord(v...)) }
+
+// Handle implements Sink.
+func (p *SyslogPrinter) Handle(r *Record) {
+	p.send(p.appendMessage(nil, r))
+}
+
+// Close closes connection to syslog server. It will be reopened on next
+// Print.
+func (p *SyslogPrinter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closeConn()
+}
+
+// appendMessage appends syslog message without framing.
+func (p *SyslogPrinter) appendMessage(buf []byte, r *Record) []byte {
+	p.mu.Lock()
+	format, facility, hostname, sdID := p.format, p.facility, p.hostname, p.sdID
+	p.mu.Unlock()
+
+	app, _ := r.Get(KeyApp)
+	if app == nil {
+		app = getAppName()
+	}
+	pid, _ := r.Get(KeyPID)
+	if pid == nil {
+		pid = os.Getpid()
+	}
+	msg, _ := r.Get(KeyMessage)
+	m := &syslogMessage{buf: buf, r: r}
+	const facilityShift = 3
+	m.buf = append(m.buf, '<')
+	m.buf = strconv.AppendInt(m.buf, int64(facility)<<facilityShift|int64(syslogSeverity(r.Level)), 10)
+	m.buf = append(m.buf, '>')
+	if format == RFC3164 {
+		m.appendRFC3164(r.Time, hostname, app, pid, msg)
+	} else {
+		m.appendRFC5424(r.Time, hostname, app, pid, msg, sdID)
+	}
+	return m.buf
+}
+
+// syslogMessage is used to build syslog message after PRI.
+type syslogMessage struct {
+	buf     []byte
+	scratch []byte
+	r       *Record
+}
+
+// str returns v in the manner of fmt.Sprint. Result is valid until next
+// call.
+func (m *syslogMessage) str(v any) []byte {
+	m.scratch = verbV.appendArg(m.scratch[:0], v)
+	return m.scratch
+}
+
+// eachKeyval calls f for each key which should be sent in addition to
+// header fields and message.
+func (m *syslogMessage) eachKeyval(f func(k string, v []byte)) {
+	for i := 0; i+1 < len(m.r.Keyvals); i += 2 {
+		switch k, _ := m.r.Keyvals[i].(string); k {
+		case KeyTime, KeyLevel, KeyApp, KeyPID, KeyMessage:
+		default:
+			f(k, m.str(m.r.Keyvals[i+1]))
+		}
+	}
+}
+
+func (m *syslogMessage) appendRFC3164(t time.Time, hostname string, app, pid, msg any) {
+	m.buf = t.AppendFormat(m.buf, time.Stamp)
+	m.buf = append(m.buf, ' ')
+	m.buf = appendSyslogField(m.buf, []byte(hostname), 255) //nolint:mnd // RFC.
+	m.buf = append(m.buf, ' ')
+	m.buf = appendSyslogField(m.buf, m.str(app), 32) //nolint:mnd // RFC.
+	m.buf = append(m.buf, '[')
+	m.buf = append(m.buf, m.str(pid)...)
+	m.buf = append(m.buf, "]: "...)
+	m.buf = append(m.buf, m.str(msg)...)
+	m.eachKeyval(func(k string, v []byte) {
+		m.buf = append(m.buf, ' ')
+		m.buf = appendLogfmtKey(m.buf, k)
+		m.buf = append(m.buf, '=')
+		m.buf = appendLogfmtValue(m.buf, v)
+	})
+}
+
+func (m *syslogMessage) appendRFC5424(t time.Time, hostname string, app, pid, msg any, sdID string) {
+	m.buf = append(m.buf, "1 "...)
+	m.buf = t.AppendFormat(m.buf, "2006-01-02T15:04:05.999999Z07:00")
+	m.buf = append(m.buf, ' ')
+	m.buf = appendSyslogField(m.buf, []byte(hostname), 255) //nolint:mnd // RFC.
+	m.buf = append(m.buf, ' ')
+	m.buf = appendSyslogField(m.buf, m.str(app), 48) //nolint:mnd // RFC.
+	m.buf = append(m.buf, ' ')
+	m.buf = appendSyslogField(m.buf, m.str(pid), 128) //nolint:mnd // RFC.
+	m.buf = append(m.buf, ' ')
+	m.buf = append(m.buf, '-', ' ') // MSGID is not used.
+	sd := len(m.buf)
+	m.buf = append(m.buf, '[')
+	m.buf = appendSDName(m.buf, sdID, len(sdID))
+	noParams := len(m.buf)
+	m.eachKeyval(func(k string, v []byte) {
+		m.buf = append(m.buf, ' ')
+		m.buf = appendSDName(m.buf, k, 32) //nolint:mnd // RFC.
+		m.buf = append(m.buf, '=', '"')
+		for _, b := range v {
+			if b == '"' || b == '\\' || b == ']' {
+				m.buf = append(m.buf, '\\')
+			}
+			m.buf = append(m.buf, b)
+		}
+		m.buf = append(m.buf, '"')
+	})
+	if len(m.buf) == noParams {
+		m.buf = append(m.buf[:sd], '-')
+	} else {
+		m.buf = append(m.buf, ']')
+	}
+	if s := m.str(msg); len(s) > 0 {
+		m.buf = append(m.buf, ' ')
+		m.buf = append(m.buf, s...)
+	}
+}
+
+// syslogSeverity returns syslog severity for level. Levels above CRT
+// (which may be added by RegisterLevel) are mapped to alert and levels
+// starting from CRT+8 to emergency.
+func syslogSeverity(level Level) int {
+	switch {
+	case level >= CRT+8:
+		return 0 // Emergency.
+	case level > CRT:
+		return 1 // Alert.
+	case level >= CRT:
+		return 2 //nolint:mnd // Critical.
+	case level >= ERR:
+		return 3 //nolint:mnd // Error.
+	case level >= WRN:
+		return 4 //nolint:mnd // Warning.
+	case level > INF:
+		return 5 //nolint:mnd // Notice.
+	case level == INF:
+		return 6 //nolint:mnd // Informational.
+	default:
+		return 7 //nolint:mnd // Debug.
+	}
+}
+
+// appendSyslogField appends v as syslog header field: non-printable
+// ASCII chars replaced by '_', truncated to maxLen, "-" if empty.
+func appendSyslogField(buf, v []byte, maxLen int) []byte {
+	if len(v) == 0 {
+		return append(buf, '-')
+	}
+	for _, b := range v[:min(len(v), maxLen)] {
+		if b <= ' ' || b > '~' {
+			b = '_'
+		}
+		buf = append(buf, b)
+	}
+	return buf
+}
+
+// appendSDName appends v as SD-NAME: chars not allowed in SD-NAME
+// replaced by '_', truncated to maxLen, "_" if empty.
+func appendSDName(buf []byte, v string, maxLen int) []byte {
+	if v == "" {
+		return append(buf, '_')
+	}
+	for _, b := range []byte(v[:min(len(v), maxLen)]) {
+		if b <= ' ' || b > '~' || b == '=' || b == ']' || b == '"' {
+			b = '_'
+		}
+		buf = append(buf, b)
+	}
+	return buf
+}
+
+// send sends msg to syslog server, reconnecting if needed.
+// In case of error msg will be output using fallback.
+func (p *SyslogPrinter) send(msg []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.write(msg)
+	if err != nil {
+		_ = p.closeConn()
+		err = p.write(msg)
+	}
+	if err != nil {
+		_ = p.closeConn()
+		p.fallback.Print(fmt.Sprintf("syslog: %v: %s", err, msg))
+	}
+}
+
+// write must be called with p.mu locked.
+func (p *SyslogPrinter) write(msg []byte) error {
+	if p.conn != nil && p.stream && isConnClosed(p.conn) {
+		_ = p.closeConn()
+	}
+	if p.conn == nil {
+		if err := p.dial(); err != nil {
+			return err
+		}
+	}
+	if p.stream && p.octetCounting {
+		msg = append(strconv.AppendInt(nil, int64(len(msg)), 10), append([]byte{' '}, msg...)...)
+	} else if p.stream {
+		msg = append(bytes.ReplaceAll(msg, []byte("\n"), []byte(`\n`)), '\n')
+	}
+	err := p.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	if err == nil {
+		_, err = p.conn.Write(msg)
+	}
+	return err
+}
+
+// dial must be called with p.mu locked.
+func (p *SyslogPrinter) dial() (err error) {
+	if p.network != "" || p.addr != "" {
+		p.conn, err = net.DialTimeout(p.network, p.addr, syslogTimeout)
+		p.stream = isStreamNetwork(p.network)
+		return err
+	}
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogPaths {
+			p.conn, err = net.DialTimeout(network, path, syslogTimeout)
+			if err == nil {
+				p.stream = isStreamNetwork(network)
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// closeConn must be called with p.mu locked.
+func (p *SyslogPrinter) closeConn() error {
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
+
+func isStreamNetwork(network string) bool {
+	return strings.HasPrefix(network, "tcp") || network == "unix"
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package structlog
+
+import "net"
+
+// isConnClosed returns false because it's not supported on this OS.
+// Closed connection will be detected on next write error.
+func isConnClosed(net.Conn) bool {
+	return false
+}
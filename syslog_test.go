@@ -0,0 +1,145 @@
+package structlog_test
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/powerman/check"
+
+	"github.com/powerman/structlog"
+)
+
+func readFramed(t *check.C, r *bufio.Reader) string {
+	t.Helper()
+	size, err := r.ReadString(' ')
+	t.Nil(err)
+	n, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+	t.Nil(err)
+	buf := make([]byte, n)
+	_, err = r.Read(buf)
+	t.Nil(err)
+	return string(buf)
+}
+
+func TestSyslogUDP(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	t.Nil(err)
+	defer ln.Close()
+
+	p := structlog.NewSyslogPrinter("udp", ln.LocalAddr().String()).SetHostname("my host")
+	defer p.Close()
+	log := structlog.New().SetPrinter(p)
+	log.Info("hello", "k", `v "q" ]`, "bad key=", 1)
+	log.New(structlog.KeyUnit, nil, structlog.KeyFunc, nil, structlog.KeySource, nil).SetLogLevel(structlog.TRC).Trace("")
+
+	buf := make([]byte, 1024)
+	n, _, err := ln.ReadFrom(buf)
+	t.Nil(err)
+	t.Equal(string(buf[:n]), `<14>1 2020-01-02T03:04:05.123456+01:00 my_host structlog.test `+pid+` - `+
+		`[structlog@32473 _u="`+unit+`" k="v \"q\" \]" bad_key_="1" _f="structlog_test.TestSyslogUDP" _s="syslog_test.go:38"] hello`)
+	n, _, err = ln.ReadFrom(buf)
+	t.Nil(err)
+	t.Equal(string(buf[:n]), `<15>1 2020-01-02T03:04:05.123456+01:00 my_host structlog.test `+pid+` - -`)
+}
+
+func TestSyslogTCP(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	t.Nil(err)
+	defer ln.Close()
+	got := make(chan string)
+	go func() {
+		for range 4 {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			got <- readFramed(t, bufio.NewReader(conn))
+			conn.Close()
+		}
+	}()
+
+	p := structlog.NewSyslogPrinter("tcp", ln.Addr().String()).
+		SetFormat(structlog.RFC3164).SetFacility(structlog.SyslogLocal0).SetHostname("host")
+	defer p.Close()
+	log := structlog.NewZeroLogger(structlog.KeyApp, "app", structlog.KeyPID, 42).SetPrinter(p).
+		SetPrefixKeys(structlog.KeyApp, structlog.KeyPID)
+	log.Err("failed", "k", "a b")
+	t.Equal(<-got, `<131>Jan  2 03:04:05 host app[42]: failed k="a b"`)
+	log.Crit("reconnected")
+	t.Equal(<-got, `<130>Jan  2 03:04:05 host app[42]: reconnected`)
+	log.Log(ALR, "alert")
+	t.Equal(<-got, `<129>Jan  2 03:04:05 host app[42]: alert`)
+	log.Log(EMR, "emergency")
+	t.Equal(<-got, `<128>Jan  2 03:04:05 host app[42]: emergency`)
+}
+
+func TestSyslogNonTransparentFraming(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	t.Nil(err)
+	defer ln.Close()
+	got := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for range 2 {
+			line, _ := r.ReadString('\n')
+			got <- line
+		}
+	}()
+
+	p := structlog.NewSyslogPrinter("tcp", ln.Addr().String()).SetHostname("host").SetOctetCounting(false)
+	defer p.Close()
+	log := structlog.NewZeroLogger().SetPrinter(p)
+	log.Err("multi\nline", structlog.KeyStack, "goroutine 1:\nmain.main()")
+	t.Equal(<-got, `<11>1 2020-01-02T03:04:05.123456+01:00 host structlog.test `+pid+` - `+
+		`[structlog@32473 __="goroutine 1:\nmain.main()"] multi\nline`+"\n")
+	log.Info("single")
+	t.Equal(<-got, `<14>1 2020-01-02T03:04:05.123456+01:00 host structlog.test `+pid+` - - single`+"\n")
+}
+
+func TestSyslogUnixgram(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	addr := filepath.Join(t.TempDir(), "log.sock")
+	ln, err := net.ListenPacket("unixgram", addr)
+	t.Nil(err)
+	defer ln.Close()
+
+	p := structlog.NewSyslogPrinter("unixgram", addr).SetHostname("host").SetStructuredDataID("x@1")
+	defer p.Close()
+	p.Print("plain", " print\n")
+	buf := make([]byte, 1024)
+	n, _, err := ln.ReadFrom(buf)
+	t.Nil(err)
+	t.Equal(string(buf[:n]), `<14>1 2020-01-02T03:04:05.123456+01:00 host structlog.test `+pid+` - - plain print`)
+}
+
+func TestSyslogFallback(tt *testing.T) {
+	t := check.T(tt)
+	t.Parallel()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	t.Nil(err)
+	addr := ln.Addr().String()
+	t.Nil(ln.Close())
+
+	var got []string
+	p := structlog.NewSyslogPrinter("tcp", addr).SetHostname("host").
+		SetFallback(structlog.PrinterFunc(func(v ...any) { got = append(got, v[0].(string)) }))
+	structlog.New().SetPrinter(p).SetDefaultKeyvals(structlog.KeyUnit, nil).Warn("lost")
+	t.Len(got, 1)
+	t.Match(got[0], `^syslog: dial tcp .*: connection refused: <12>1 .* host structlog.test `+pid+` - \[.*\] lost$`)
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+package structlog
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// isConnClosed returns true if stream connection was closed by server.
+func isConnClosed(conn net.Conn) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return true
+	}
+	closed := false
+	err = rc.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK) //nolint:gosec // File descriptor fits int.
+		closed = err == nil && n == 0 || err != nil && !errors.Is(err, syscall.EAGAIN)
+		return true // Socket is non-blocking, so don't wait for data.
+	})
+	return err != nil || closed
+}
@@ -0,0 +1,7 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package structlog
+
+import "golang.org/x/sys/unix"
+
+const ioctlGetTermios = unix.TIOCGETA
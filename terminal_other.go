@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package structlog
+
+import "os"
+
+// isTerminalFile returns false because terminals are not supported on this OS.
+func isTerminalFile(*os.File) bool { return false }
@@ -0,0 +1,7 @@
+//go:build aix || linux || solaris || zos
+
+package structlog
+
+import "golang.org/x/sys/unix"
+
+const ioctlGetTermios = unix.TCGETS
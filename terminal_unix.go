@@ -0,0 +1,22 @@
+//go:build unix
+
+package structlog
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// isTerminalFile returns true if f is a terminal.
+func isTerminalFile(f *os.File) bool {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var ioctlErr error
+	err = rc.Control(func(fd uintptr) {
+		_, ioctlErr = unix.IoctlGetTermios(int(fd), ioctlGetTermios) //nolint:gosec // File descriptor fits int.
+	})
+	return err == nil && ioctlErr == nil
+}
@@ -0,0 +1,21 @@
+package structlog
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// isTerminalFile returns true if f is a console.
+func isTerminalFile(f *os.File) bool {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var mode uint32
+	var consoleErr error
+	err = rc.Control(func(fd uintptr) {
+		consoleErr = windows.GetConsoleMode(windows.Handle(fd), &mode)
+	})
+	return err == nil && consoleErr == nil
+}
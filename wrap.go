@@ -46,6 +46,7 @@ func (l *Logger) WrapErr(err error, keyvals ...any) error {
 		return nil
 	}
 
+	keyvals = expandAttrs(keyvals)
 	if len(keyvals)%2 != 0 {
 		l.New().AddCallDepth(getPackageDepth()).PrintErr("odd keyvals")
 		keyvals = append(keyvals, MissingValue)